@@ -22,6 +22,7 @@ func rootCmd() *cobra.Command {
 	}
 
 	cmd.AddCommand(runCmd())
+	cmd.AddCommand(resolveCmd())
 	cmd.AddCommand(execCmd()) // Deprecated
 
 	return cmd
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/busser/murmur/pkg/environ"
+	"github.com/busser/murmur/pkg/murmur"
+	"github.com/busser/murmur/pkg/slices"
+	"github.com/spf13/cobra"
+)
+
+func resolveCmd() *cobra.Command {
+	var (
+		format         string
+		overloadedOnly bool
+		formatOpts     environ.FormatOptions
+		opts           resolveOptions
+	)
+
+	formats := make([]string, len(environ.Formats))
+	for i, f := range environ.Formats {
+		formats[i] = string(f)
+	}
+
+	cmd := &cobra.Command{
+		Use:  "resolve",
+		Args: cobra.NoArgs,
+
+		Short: "Print environment variables with secrets injected",
+
+		Example: `  # Pass secrets to a container:
+  export PGPASSWORD="scwsm:database-password"
+  murmur resolve --overloaded-only --format docker-env > .env
+  docker run --env-file .env postgres
+
+  # Pass secrets to later steps of a GitHub Actions job:
+  murmur resolve --overloaded-only --format github-env >> "$GITHUB_ENV"
+
+  # Store secrets in a Kubernetes Secret:
+  murmur resolve --overloaded-only --format k8s-secret --secret-name db | kubectl apply -f -`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Fail before fetching any secrets.
+			if !slices.Contains(environ.Formats, environ.Format(format)) {
+				return fmt.Errorf("unknown format %q, must be one of: %s", format, strings.Join(formats, ", "))
+			}
+			murmurOpts, err := opts.murmurOptions()
+			if err != nil {
+				return err
+			}
+
+			originalVars := environ.ToMap(os.Environ())
+
+			newVars, err := murmur.ResolveAll(originalVars, murmurOpts...)
+			if err != nil {
+				return err
+			}
+
+			if overloadedOnly {
+				for name, original := range originalVars {
+					if newVars[name] == original {
+						delete(newVars, name)
+					}
+				}
+			}
+
+			return environ.Write(cmd.OutOrStdout(), newVars, environ.Format(format), formatOpts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", string(environ.FormatDotenv),
+		fmt.Sprintf("output format (%s)", strings.Join(formats, "|")))
+	cmd.Flags().BoolVar(&overloadedOnly, "overloaded-only", false,
+		"only print variables whose value murmur changed")
+	cmd.Flags().StringVar(&formatOpts.SecretName, "secret-name", "murmur",
+		"name of the Secret in the k8s-secret format")
+	cmd.Flags().StringVar(&formatOpts.SecretNamespace, "secret-namespace", "",
+		"namespace of the Secret in the k8s-secret format")
+	opts.addFlags(cmd.Flags())
+
+	return cmd
+}
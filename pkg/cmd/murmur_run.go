@@ -8,6 +8,11 @@ import (
 )
 
 func runCmd() *cobra.Command {
+	var (
+		opts   resolveOptions
+		redact bool
+	)
+
 	cmd := &cobra.Command{
 		Use:  "run -- command [args...]",
 		Args: cobra.MinimumNArgs(1),
@@ -22,10 +27,27 @@ func runCmd() *cobra.Command {
   
   # Build a connection string from a JSON secret:
   export PGDATABASE="scwsm:database-credentials|jsonpath:{.username}:{password}@{.host}:{.port}/{.database}" 
-  murmur run -- psql`,
+  murmur run -- psql
+
+  # Only resolve values explicitly marked as queries:
+  export PGPASSWORD="murmur+scwsm:database-password"
+  export PGAPPNAME="op:billing"
+  murmur run --strict -- psql
+
+  # Hide secrets from the command's output:
+  murmur run --redact -- ./my-app`,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
-			exitCode, err := murmur.Run(args[0], args[1:]...)
+			murmurOpts, err := opts.murmurOptions()
+			if err != nil {
+				return err
+			}
+
+			if redact {
+				murmurOpts = append(murmurOpts, murmur.WithRedaction())
+			}
+
+			exitCode, err := murmur.RunWithOptions(args[0], args[1:], murmurOpts...)
 			if err != nil {
 				return err
 			}
@@ -34,5 +56,9 @@ func runCmd() *cobra.Command {
 		},
 	}
 
+	opts.addFlags(cmd.Flags())
+	cmd.Flags().BoolVar(&redact, "redact", false,
+		"replace secrets in the command's output with ***, including their base64, URL, and JSON encodings")
+
 	return cmd
 }
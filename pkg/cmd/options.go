@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/busser/murmur/pkg/murmur"
+	"github.com/spf13/pflag"
+)
+
+// resolveOptions holds the flags shared by all commands that resolve secrets.
+type resolveOptions struct {
+	strictPrefix   bool
+	failOnUnknown  bool
+	interpolate    bool
+	timeout        time.Duration
+	retryPolicy    murmur.RetryPolicy
+	maxConcurrency int
+	// Provider settings, like "awssm.region=eu-west-3".
+	providerConfig []string
+}
+
+func (o *resolveOptions) addFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.strictPrefix, "strict", false,
+		"only resolve queries that start with "+murmur.QueryPrefix)
+	flags.BoolVar(&o.failOnUnknown, "fail-on-unknown", false,
+		"fail on values that look like queries with a mistyped provider or filter")
+	flags.BoolVar(&o.interpolate, "interpolate", false,
+		"resolve queries embedded in values with the ${query} syntax")
+	flags.DurationVar(&o.timeout, "timeout", 0,
+		"give up on fetching secrets after this long, like 30s (0 means no limit)")
+	flags.IntVar(&o.maxConcurrency, "max-concurrency", 0,
+		"fetch at most this many secrets at once from each provider (0 means each provider's default)")
+
+	flags.StringArrayVar(&o.providerConfig, "provider-config", nil,
+		"set a provider setting, like awssm.region=eu-west-3 (can be repeated)")
+
+	defaults := murmur.DefaultRetryPolicy
+	flags.IntVar(&o.retryPolicy.MaxAttempts, "retry-attempts", defaults.MaxAttempts,
+		"how many times to try fetching a secret when providers fail with transient errors (1 disables retries)")
+	flags.DurationVar(&o.retryPolicy.InitialBackoff, "retry-backoff", defaults.InitialBackoff,
+		"how long to wait before the first retry, doubling after each retry")
+	flags.DurationVar(&o.retryPolicy.MaxBackoff, "retry-max-backoff", defaults.MaxBackoff,
+		"the longest to wait between retries (0 means no limit)")
+	flags.Float64Var(&o.retryPolicy.Jitter, "retry-jitter", defaults.Jitter,
+		"the random fraction of each wait between retries, from 0 to 1")
+}
+
+func (o *resolveOptions) murmurOptions() ([]murmur.Option, error) {
+	logger, err := murmur.NewLogger(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []murmur.Option{
+		murmur.WithRetryPolicy(o.retryPolicy),
+		murmur.WithLogger(logger),
+	}
+	if o.strictPrefix {
+		opts = append(opts, murmur.WithStrictPrefix())
+	}
+	if o.failOnUnknown {
+		opts = append(opts, murmur.WithFailOnUnknown())
+	}
+	if o.interpolate {
+		opts = append(opts, murmur.WithInterpolation())
+	}
+	if o.timeout > 0 {
+		opts = append(opts, murmur.WithTimeout(o.timeout))
+	}
+	if o.maxConcurrency > 0 {
+		opts = append(opts, murmur.WithMaxConcurrency(o.maxConcurrency))
+	}
+	for _, setting := range o.providerConfig {
+		providerID, name, value, err := parseProviderSetting(setting)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, murmur.WithProviderConfig(providerID, murmur.ProviderConfig{name: value}))
+	}
+
+	providers, err := murmur.PluginProviders(murmur.ProviderFactories, logger)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, murmur.WithProviders(providers))
+
+	return opts, nil
+}
+
+// parseProviderSetting parses settings like "awssm.region=eu-west-3". Names
+// may use hyphens or underscores, in any case.
+func parseProviderSetting(s string) (providerID, name, value string, err error) {
+	key, value, found := strings.Cut(s, "=")
+	if !found {
+		return "", "", "", fmt.Errorf("invalid provider setting %q: must look like provider.setting=value", s)
+	}
+	providerID, name, found = strings.Cut(key, ".")
+	if !found || providerID == "" || name == "" {
+		return "", "", "", fmt.Errorf("invalid provider setting %q: must look like provider.setting=value", s)
+	}
+	name = strings.ToLower(strings.ReplaceAll(name, "-", "_"))
+	return providerID, name, value, nil
+}
@@ -0,0 +1,332 @@
+package environ
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// A Format is a way of writing environment variables to a file.
+type Format string
+
+const (
+	// FormatDotenv writes KEY=value lines, as read by docker compose and most
+	// dotenv libraries, like python-dotenv and godotenv. Values are quoted
+	// only when necessary, with single quotes, or double quotes and escapes
+	// for values that contain single quotes or line breaks. Values with "$"
+	// are single-quoted, so that no loader expands variables in them. When
+	// that is not possible, "$" is escaped as "\$", the way docker compose and
+	// godotenv expect; python-dotenv keeps the backslash.
+	FormatDotenv Format = "dotenv"
+	// FormatDockerEnv writes KEY=value lines with values as-is, as read by
+	// "docker run --env-file" and "kubectl create secret --from-env-file".
+	// These tools keep quotes as part of values, and have no escapes, so they
+	// cannot read values with line breaks.
+	FormatDockerEnv Format = "docker-env"
+	// FormatJSON writes a JSON object.
+	FormatJSON Format = "json"
+	// FormatYAML writes a YAML mapping.
+	FormatYAML Format = "yaml"
+	// FormatShellExport writes export statements that POSIX shells can eval.
+	FormatShellExport Format = "shell-export"
+	// FormatGitHubEnv writes the syntax of GitHub Actions' $GITHUB_ENV file.
+	FormatGitHubEnv Format = "github-env"
+	// FormatK8sSecret writes a Kubernetes Secret manifest.
+	FormatK8sSecret Format = "k8s-secret"
+)
+
+// Formats lists all supported formats.
+var Formats = []Format{
+	FormatDotenv,
+	FormatDockerEnv,
+	FormatJSON,
+	FormatYAML,
+	FormatShellExport,
+	FormatGitHubEnv,
+	FormatK8sSecret,
+}
+
+// FormatOptions tweak how environment variables are written.
+type FormatOptions struct {
+	// SecretName is the name of the Secret written in the k8s-secret format.
+	SecretName string
+	// SecretNamespace is the namespace of the Secret written in the k8s-secret
+	// format. The manifest has no namespace if this is empty.
+	SecretNamespace string
+}
+
+// Write writes env to w in the given format, sorted by variable name.
+func Write(w io.Writer, env map[string]string, format Format, opts FormatOptions) error {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var (
+		out string
+		err error
+	)
+	switch format {
+	case FormatDotenv:
+		out, err = formatDotenv(keys, env)
+	case FormatDockerEnv:
+		out, err = formatDockerEnv(keys, env)
+	case FormatJSON:
+		out, err = formatJSON(keys, env)
+	case FormatYAML:
+		out, err = formatYAML(keys, env)
+	case FormatShellExport:
+		out, err = formatShellExport(keys, env)
+	case FormatGitHubEnv:
+		out, err = formatGitHubEnv(keys, env)
+	case FormatK8sSecret:
+		out, err = formatK8sSecret(keys, env, opts)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, out)
+	return err
+}
+
+var (
+	shellNameRegexp     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	k8sSecretKeyRegexp  = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+	yamlBareRegexp      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+	dotenvBareRegexp    = regexp.MustCompile(`^[^\s"'\x60$#\\]*$`)
+	dotenvSingleRegexp  = regexp.MustCompile(`^[^'\n\r]*$`)
+	dotenvDoubleEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, `$`, `\$`)
+)
+
+func formatDotenv(keys []string, env map[string]string) (string, error) {
+	var b strings.Builder
+
+	for _, k := range keys {
+		if !shellNameRegexp.MatchString(k) {
+			return "", fmt.Errorf("variable name %q is not valid in dotenv files", k)
+		}
+
+		v := env[k]
+		switch {
+		case dotenvBareRegexp.MatchString(v):
+			fmt.Fprintf(&b, "%s=%s\n", k, v)
+		case dotenvSingleRegexp.MatchString(v):
+			fmt.Fprintf(&b, "%s='%s'\n", k, v)
+		default:
+			fmt.Fprintf(&b, "%s=\"%s\"\n", k, dotenvDoubleEscaper.Replace(v))
+		}
+	}
+
+	return b.String(), nil
+}
+
+func formatDockerEnv(keys []string, env map[string]string) (string, error) {
+	var b strings.Builder
+
+	for _, k := range keys {
+		if !shellNameRegexp.MatchString(k) {
+			return "", fmt.Errorf("variable name %q is not valid in env files", k)
+		}
+
+		v := env[k]
+		if strings.ContainsAny(v, "\n\r") {
+			return "", fmt.Errorf("value of %s contains a line break, which the %s format cannot represent; use the %s format instead", k, FormatDockerEnv, FormatDotenv)
+		}
+		if !utf8.ValidString(v) {
+			return "", fmt.Errorf("value of %s is not valid UTF-8, which docker cannot read", k)
+		}
+		fmt.Fprintf(&b, "%s=%s\n", k, v)
+	}
+
+	return b.String(), nil
+}
+
+func formatJSON(keys []string, env map[string]string) (string, error) {
+	for _, k := range keys {
+		if !utf8.ValidString(env[k]) {
+			return "", fmt.Errorf("value of %s is not valid UTF-8, which JSON cannot represent", k)
+		}
+	}
+
+	var b strings.Builder
+
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(env); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+func formatYAML(keys []string, env map[string]string) (string, error) {
+	if len(keys) == 0 {
+		return "{}\n", nil
+	}
+
+	var b strings.Builder
+
+	for _, k := range keys {
+		v := env[k]
+		if !utf8.ValidString(v) {
+			return "", fmt.Errorf("value of %s is not valid UTF-8, which YAML cannot represent", k)
+		}
+		fmt.Fprintf(&b, "%s: %s\n", yamlString(k), yamlString(v))
+	}
+
+	return b.String(), nil
+}
+
+// yamlString returns s as a YAML scalar, quoted only if necessary.
+func yamlString(s string) string {
+	if yamlBareRegexp.MatchString(s) && !yamlReservedWords[strings.ToLower(s)] {
+		return s
+	}
+	return yamlQuote(s)
+}
+
+// yamlReservedWords would be parsed as booleans or null if not quoted, at least
+// by YAML 1.1 parsers.
+var yamlReservedWords = map[string]bool{
+	"y": true, "yes": true, "n": true, "no": true,
+	"true": true, "false": true, "on": true, "off": true,
+	"null": true,
+}
+
+// yamlQuote returns s as a double-quoted YAML scalar. Double-quoted scalars
+// are the only kind that can represent any string.
+func yamlQuote(s string) string {
+	var b strings.Builder
+
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"':
+			b.WriteString(`\"`)
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case yamlPrintable(r):
+			b.WriteRune(r)
+		case r <= 0xFF:
+			fmt.Fprintf(&b, `\x%02X`, r)
+		case r <= 0xFFFF:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			fmt.Fprintf(&b, `\U%08X`, r)
+		}
+	}
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+// yamlPrintable reports whether r may appear as-is in a YAML document. Line
+// breaks other than '\n' are not considered printable, since parsers would
+// fold them.
+func yamlPrintable(r rune) bool {
+	switch {
+	case r >= 0x20 && r <= 0x7E:
+		return true
+	case r >= 0xA0 && r <= 0xD7FF:
+		return r != 0x2028 && r != 0x2029
+	case r >= 0xE000 && r <= 0xFFFD:
+		return r != 0xFEFF
+	case r >= 0x10000 && r <= 0x10FFFF:
+		return true
+	default:
+		return false
+	}
+}
+
+func formatShellExport(keys []string, env map[string]string) (string, error) {
+	var b strings.Builder
+
+	for _, k := range keys {
+		if !shellNameRegexp.MatchString(k) {
+			return "", fmt.Errorf("variable name %q is not valid in shells", k)
+		}
+
+		// Single quotes preserve everything literally, except single quotes
+		// themselves. Those end the quoted string, get escaped, and start a
+		// new quoted string.
+		v := strings.ReplaceAll(env[k], `'`, `'\''`)
+		fmt.Fprintf(&b, "export %s='%s'\n", k, v)
+	}
+
+	return b.String(), nil
+}
+
+func formatGitHubEnv(keys []string, env map[string]string) (string, error) {
+	var b strings.Builder
+
+	for _, k := range keys {
+		if strings.ContainsAny(k, "=\n\r") || strings.Contains(k, "<<") {
+			return "", fmt.Errorf("variable name %q is not valid in GitHub Actions", k)
+		}
+
+		v := env[k]
+		if !strings.ContainsAny(v, "\n\r") {
+			fmt.Fprintf(&b, "%s=%s\n", k, v)
+			continue
+		}
+
+		// Multi-line values need a delimiter that the value does not contain.
+		// GitHub recommends a random one.
+		delimiter := "ghadelimiter_" + uuid.NewString()
+		for strings.Contains(v, delimiter) {
+			delimiter = "ghadelimiter_" + uuid.NewString()
+		}
+		fmt.Fprintf(&b, "%s<<%s\n%s\n%s\n", k, delimiter, v, delimiter)
+	}
+
+	return b.String(), nil
+}
+
+func formatK8sSecret(keys []string, env map[string]string, opts FormatOptions) (string, error) {
+	if opts.SecretName == "" {
+		return "", fmt.Errorf("the %s format requires a secret name", FormatK8sSecret)
+	}
+
+	var b strings.Builder
+
+	b.WriteString("apiVersion: v1\n")
+	b.WriteString("kind: Secret\n")
+	b.WriteString("metadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", yamlString(opts.SecretName))
+	if opts.SecretNamespace != "" {
+		fmt.Fprintf(&b, "  namespace: %s\n", yamlString(opts.SecretNamespace))
+	}
+	b.WriteString("type: Opaque\n")
+
+	if len(keys) == 0 {
+		b.WriteString("data: {}\n")
+		return b.String(), nil
+	}
+
+	// Base64-encoded data represents any value exactly, binary or not.
+	b.WriteString("data:\n")
+	for _, k := range keys {
+		if !k8sSecretKeyRegexp.MatchString(k) {
+			return "", fmt.Errorf("variable name %q is not a valid Secret key", k)
+		}
+		fmt.Fprintf(&b, "  %s: %s\n", yamlString(k), base64.StdEncoding.EncodeToString([]byte(env[k])))
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,291 @@
+package environ
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.yaml.in/yaml/v3"
+)
+
+// trickyValues contain characters that each format must quote or escape.
+var trickyValues = map[string]string{
+	"EMPTY":      "",
+	"PLAIN":      "szechuan",
+	"SPACES":     "  sweet and sour  ",
+	"QUOTES":     `it's "secret"`,
+	"SHELL":      "$HOME `whoami` $(id) \\ # not a comment",
+	"MULTILINE":  "first line\nsecond line\n",
+	"CONTROL":    "tab\there, bell\a, del\x7f, nel\u0085, ls\u2028",
+	"UNICODE":    "sauce piquante 🌶️",
+	"YAML_LIKE":  "true",
+	"JSON_LIKE":  `{"key": "value"}`,
+	"LEADING_NL": "\nvalue",
+}
+
+func TestWriteDotenv(t *testing.T) {
+	env := map[string]string{
+		"PLAIN":     "szechuan",
+		"EMPTY":     "",
+		"SPACES":    "sweet and sour",
+		"QUOTES":    `it's "secret"`,
+		"DOLLAR":    "$HOME",
+		"TIP":       "chef's ${TIP}",
+		"MULTILINE": "first line\nsecond line",
+		"URL":       "postgres://chef:ketchup@db:5432/kitchen?sslmode=require",
+	}
+
+	want := `DOLLAR='$HOME'
+EMPTY=
+MULTILINE="first line\nsecond line"
+PLAIN=szechuan
+QUOTES="it's \"secret\""
+SPACES='sweet and sour'
+TIP="chef's \${TIP}"
+URL=postgres://chef:ketchup@db:5432/kitchen?sslmode=require
+`
+
+	var b strings.Builder
+	if err := Write(&b, env, FormatDotenv, FormatOptions{}); err != nil {
+		t.Fatalf("Write() returned an error: %v", err)
+	}
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("Write() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteDockerEnv(t *testing.T) {
+	env := make(map[string]string)
+	for k, v := range trickyValues {
+		if !strings.ContainsAny(v, "\n\r") {
+			env[k] = v
+		}
+	}
+
+	var b strings.Builder
+	if err := Write(&b, env, FormatDockerEnv, FormatOptions{}); err != nil {
+		t.Fatalf("Write() returned an error: %v", err)
+	}
+
+	// Parse the output like "docker run --env-file" does: each line is a
+	// variable name, an "=", and the value, as-is.
+	actual := make(map[string]string)
+	for line := range strings.Lines(b.String()) {
+		k, v, _ := strings.Cut(strings.TrimSuffix(line, "\n"), "=")
+		actual[k] = v
+	}
+	if diff := cmp.Diff(env, actual); diff != "" {
+		t.Errorf("Write() output does not decode to the input (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var b strings.Builder
+	if err := Write(&b, trickyValues, FormatJSON, FormatOptions{}); err != nil {
+		t.Fatalf("Write() returned an error: %v", err)
+	}
+
+	var actual map[string]string
+	if err := json.Unmarshal([]byte(b.String()), &actual); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, b.String())
+	}
+	if diff := cmp.Diff(trickyValues, actual); diff != "" {
+		t.Errorf("Write() output does not decode to the input (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	var b strings.Builder
+	if err := Write(&b, trickyValues, FormatYAML, FormatOptions{}); err != nil {
+		t.Fatalf("Write() returned an error: %v", err)
+	}
+
+	var actual map[string]string
+	if err := yaml.Unmarshal([]byte(b.String()), &actual); err != nil {
+		t.Fatalf("output is not valid YAML: %v\n%s", err, b.String())
+	}
+	if diff := cmp.Diff(trickyValues, actual); diff != "" {
+		t.Errorf("Write() output does not decode to the input (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteShellExport(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	var b strings.Builder
+	if err := Write(&b, trickyValues, FormatShellExport, FormatOptions{}); err != nil {
+		t.Fatalf("Write() returned an error: %v", err)
+	}
+
+	// The shell evaluates the output, then prints each variable's value
+	// followed by a null byte.
+	script := b.String()
+	for _, k := range sortedKeys(trickyValues) {
+		script += `printf '%s\0' "$` + k + `"` + "\n"
+	}
+
+	out, err := exec.Command("sh", "-c", script).Output()
+	if err != nil {
+		t.Fatalf("shell failed to evaluate output: %v\n%s", err, b.String())
+	}
+
+	values := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	actual := make(map[string]string)
+	for i, k := range sortedKeys(trickyValues) {
+		actual[k] = values[i]
+	}
+	if diff := cmp.Diff(trickyValues, actual); diff != "" {
+		t.Errorf("Write() output does not evaluate to the input (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteGitHubEnv(t *testing.T) {
+	env := map[string]string{
+		"PLAIN":     "szechuan",
+		"MULTILINE": "first line\nsecond line",
+	}
+
+	var b strings.Builder
+	if err := Write(&b, env, FormatGitHubEnv, FormatOptions{}); err != nil {
+		t.Fatalf("Write() returned an error: %v", err)
+	}
+
+	wantRegexp := regexp.MustCompile(`^MULTILINE<<(ghadelimiter_[-0-9a-f]+)\n` +
+		`first line\nsecond line\n` +
+		`(ghadelimiter_[-0-9a-f]+)\n` +
+		`PLAIN=szechuan\n$`)
+
+	matches := wantRegexp.FindStringSubmatch(b.String())
+	if matches == nil {
+		t.Fatalf("Write() output does not match %s:\n%s", wantRegexp, b.String())
+	}
+	if matches[1] != matches[2] {
+		t.Errorf("opening delimiter %q and closing delimiter %q differ", matches[1], matches[2])
+	}
+}
+
+func TestWriteK8sSecret(t *testing.T) {
+	var b strings.Builder
+	opts := FormatOptions{SecretName: "kitchen-secrets", SecretNamespace: "restaurant"}
+	if err := Write(&b, trickyValues, FormatK8sSecret, opts); err != nil {
+		t.Fatalf("Write() returned an error: %v", err)
+	}
+
+	var secret struct {
+		APIVersion string `yaml:"apiVersion"`
+		Kind       string `yaml:"kind"`
+		Metadata   struct {
+			Name      string `yaml:"name"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"metadata"`
+		Data map[string]string `yaml:"data"`
+	}
+	if err := yaml.Unmarshal([]byte(b.String()), &secret); err != nil {
+		t.Fatalf("output is not valid YAML: %v\n%s", err, b.String())
+	}
+
+	if secret.APIVersion != "v1" || secret.Kind != "Secret" {
+		t.Errorf("Write() wrote a %s %s, want a v1 Secret", secret.APIVersion, secret.Kind)
+	}
+	if secret.Metadata.Name != opts.SecretName || secret.Metadata.Namespace != opts.SecretNamespace {
+		t.Errorf("Write() wrote Secret %s/%s, want %s/%s",
+			secret.Metadata.Namespace, secret.Metadata.Name, opts.SecretNamespace, opts.SecretName)
+	}
+
+	actual := make(map[string]string)
+	for k, v := range secret.Data {
+		decoded, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			t.Fatalf("value of %s is not valid base64: %v", k, err)
+		}
+		actual[k] = string(decoded)
+	}
+	if diff := cmp.Diff(trickyValues, actual); diff != "" {
+		t.Errorf("Write() output does not decode to the input (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	tt := []struct {
+		name   string
+		env    map[string]string
+		format Format
+		opts   FormatOptions
+	}{
+		{
+			name:   "unknown format",
+			env:    map[string]string{"A": "a"},
+			format: "xml",
+		},
+		{
+			name:   "invalid shell name",
+			env:    map[string]string{"NOT-VALID": "a"},
+			format: FormatShellExport,
+		},
+		{
+			name:   "invalid dotenv name",
+			env:    map[string]string{"1A": "a"},
+			format: FormatDotenv,
+		},
+		{
+			name:   "line break in docker env file",
+			env:    map[string]string{"A": "first line\nsecond line"},
+			format: FormatDockerEnv,
+		},
+		{
+			name:   "invalid UTF-8 in docker env file",
+			env:    map[string]string{"A": "\xff"},
+			format: FormatDockerEnv,
+		},
+		{
+			name:   "invalid UTF-8 in JSON",
+			env:    map[string]string{"A": "\xff"},
+			format: FormatJSON,
+		},
+		{
+			name:   "invalid UTF-8 in YAML",
+			env:    map[string]string{"A": "\xff"},
+			format: FormatYAML,
+		},
+		{
+			name:   "missing secret name",
+			env:    map[string]string{"A": "a"},
+			format: FormatK8sSecret,
+		},
+		{
+			name:   "invalid secret key",
+			env:    map[string]string{"A B": "a"},
+			format: FormatK8sSecret,
+			opts:   FormatOptions{SecretName: "kitchen-secrets"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := Write(&b, tc.env, tc.format, tc.opts); err == nil {
+				t.Errorf("Write() did not return an error, wrote:\n%s", b.String())
+			}
+			if b.Len() != 0 {
+				t.Errorf("Write() wrote output despite failing:\n%s", b.String())
+			}
+		})
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,231 @@
+package murmur
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/busser/murmur/pkg/murmur/providers/awsps"
+	"github.com/busser/murmur/pkg/murmur/providers/awssm"
+	"github.com/busser/murmur/pkg/murmur/providers/azkv"
+	"github.com/busser/murmur/pkg/murmur/providers/doppler"
+	"github.com/busser/murmur/pkg/murmur/providers/gcpsm"
+	"github.com/busser/murmur/pkg/murmur/providers/localfile"
+	"github.com/busser/murmur/pkg/murmur/providers/op"
+	"github.com/busser/murmur/pkg/murmur/providers/scwsm"
+	"github.com/busser/murmur/pkg/murmur/providers/vault"
+)
+
+// A ProviderConfig holds settings for a provider, like its region or endpoint,
+// by name. Names are lowercase, with words separated by underscores, like
+// "region" or "credentials_file".
+//
+// Murmur reads settings from environment variables named after the provider's
+// ID and the setting's name, like MURMUR_AWSSM_REGION for the "region" setting
+// of the "awssm" provider. WithProviderConfig sets them too.
+type ProviderConfig map[string]string
+
+// providerConfigEnvPrefix returns the prefix of environment variables holding
+// settings for the provider with the given ID.
+func providerConfigEnvPrefix(providerID string) string {
+	return "MURMUR_" + strings.ToUpper(strings.ReplaceAll(providerID, "-", "_")) + "_"
+}
+
+// providerConfigFromEnv returns the settings for the provider with the given ID
+// found in environ, a list of "KEY=value" strings.
+func providerConfigFromEnv(providerID string, environ []string) ProviderConfig {
+	prefix := providerConfigEnvPrefix(providerID)
+
+	config := make(ProviderConfig)
+	for _, kv := range environ {
+		key, value, _ := strings.Cut(kv, "=")
+		name, ok := strings.CutPrefix(key, prefix)
+		// Like unset variables, empty ones leave settings to their default.
+		if !ok || name == "" || value == "" {
+			continue
+		}
+		config[strings.ToLower(name)] = value
+	}
+
+	return config
+}
+
+// providerConfig returns the settings for the provider with the given ID, from
+// the environment and from options. Options take precedence.
+func (o options) providerConfig(providerID string) ProviderConfig {
+	config := providerConfigFromEnv(providerID, os.Environ())
+	maps.Copy(config, o.providerConfigs[providerID])
+	return config
+}
+
+// bind sets each setting to its value in the config. Settings are pointers to
+// strings or booleans, by name. bind fails if the config has values for
+// unknown settings, which are probably typos.
+func (c ProviderConfig) bind(settings map[string]any) error {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := c[name]
+
+		setting, ok := settings[name]
+		if !ok {
+			if len(settings) == 0 {
+				return fmt.Errorf("unknown setting %q: this provider has no settings", name)
+			}
+			if suggestion, found := suggest(name, keys(settings)); found {
+				return fmt.Errorf("unknown setting %q, did you mean %q?", name, suggestion)
+			}
+			return fmt.Errorf("unknown setting %q, known settings are %s", name, strings.Join(keys(settings), ", "))
+		}
+
+		switch p := setting.(type) {
+		case *string:
+			*p = value
+		case *bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("setting %q must be true or false, not %q", name, value)
+			}
+			*p = b
+		default:
+			panic(fmt.Sprintf("unsupported type %T for setting %q", setting, name))
+		}
+	}
+
+	return nil
+}
+
+// withoutConfig returns a ProviderFactory for providers that have no settings.
+func withoutConfig[P Provider](newProvider func() (P, error)) ProviderFactory {
+	return func(_ context.Context, config ProviderConfig) (Provider, error) {
+		if err := config.bind(nil); err != nil {
+			return nil, err
+		}
+		return newProvider()
+	}
+}
+
+func newAWSSecretsManager(ctx context.Context, config ProviderConfig) (Provider, error) {
+	var opts awssm.Options
+	err := config.bind(map[string]any{
+		"region":               &opts.Region,
+		"endpoint":             &opts.Endpoint,
+		"profile":              &opts.Profile,
+		"insecure_skip_verify": &opts.InsecureSkipVerify,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return awssm.NewWithOptions(ctx, opts)
+}
+
+func newAWSParameterStore(ctx context.Context, config ProviderConfig) (Provider, error) {
+	var opts awsps.Options
+	withDecryption := true
+	err := config.bind(map[string]any{
+		"region":               &opts.Region,
+		"endpoint":             &opts.Endpoint,
+		"profile":              &opts.Profile,
+		"with_decryption":      &withDecryption,
+		"insecure_skip_verify": &opts.InsecureSkipVerify,
+	})
+	if err != nil {
+		return nil, err
+	}
+	opts.DisableDecryption = !withDecryption
+	return awsps.NewWithOptions(ctx, opts)
+}
+
+func newGCPSecretManager(ctx context.Context, config ProviderConfig) (Provider, error) {
+	var opts gcpsm.Options
+	err := config.bind(map[string]any{
+		"endpoint":             &opts.Endpoint,
+		"credentials_file":     &opts.CredentialsFile,
+		"insecure_skip_verify": &opts.InsecureSkipVerify,
+		"insecure":             &opts.Insecure,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return gcpsm.NewWithOptions(ctx, opts)
+}
+
+func newAzureKeyVault(_ context.Context, config ProviderConfig) (Provider, error) {
+	var opts azkv.Options
+	err := config.bind(map[string]any{
+		"tenant_id":            &opts.TenantID,
+		"endpoint":             &opts.Endpoint,
+		"insecure_skip_verify": &opts.InsecureSkipVerify,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return azkv.NewWithOptions(opts)
+}
+
+func newScalewaySecretManager(_ context.Context, config ProviderConfig) (Provider, error) {
+	var opts scwsm.Options
+	err := config.bind(map[string]any{
+		"profile":              &opts.Profile,
+		"region":               &opts.Region,
+		"endpoint":             &opts.Endpoint,
+		"insecure_skip_verify": &opts.InsecureSkipVerify,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return scwsm.NewWithOptions(opts)
+}
+
+func newVault(ctx context.Context, config ProviderConfig) (Provider, error) {
+	var opts vault.Options
+	err := config.bind(map[string]any{
+		"insecure_skip_verify": &opts.InsecureSkipVerify,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vault.NewWithOptions(ctx, opts)
+}
+
+func newLocalFile(_ context.Context, config ProviderConfig) (Provider, error) {
+	var opts localfile.Options
+	err := config.bind(map[string]any{
+		"trim_newline": &opts.TrimNewline,
+		"strict":       &opts.Strict,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return localfile.NewWithOptions(opts)
+}
+
+func newOnePasswordConnect(_ context.Context, config ProviderConfig) (Provider, error) {
+	var opts op.Options
+	err := config.bind(map[string]any{
+		"insecure_skip_verify": &opts.InsecureSkipVerify,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return op.NewWithOptions(opts)
+}
+
+func newDoppler(_ context.Context, config ProviderConfig) (Provider, error) {
+	var opts doppler.Options
+	err := config.bind(map[string]any{
+		"insecure_skip_verify": &opts.InsecureSkipVerify,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doppler.NewWithOptions(opts)
+}
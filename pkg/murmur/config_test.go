@@ -0,0 +1,190 @@
+package murmur
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/busser/murmur/pkg/murmur/providers/mock"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProviderConfigFromEnv(t *testing.T) {
+	environ := []string{
+		"MURMUR_AWSSM_REGION=eu-west-3",
+		"MURMUR_AWSSM_ENDPOINT=http://localhost:4566",
+		"MURMUR_AWSSM_=empty name",
+		"MURMUR_AWSPS_REGION=us-east-1",
+		"MURMUR_MY_CORP_CREDENTIALS_FILE=/etc/mycorp.json",
+		"MURMUR_PROVIDER_PLUGINS=/opt/plugins",
+		"AWS_REGION=us-west-2",
+	}
+
+	tt := []struct {
+		providerID string
+		want       ProviderConfig
+	}{
+		{
+			providerID: "awssm",
+			want: ProviderConfig{
+				"region":   "eu-west-3",
+				"endpoint": "http://localhost:4566",
+			},
+		},
+		{
+			providerID: "my-corp",
+			want: ProviderConfig{
+				"credentials_file": "/etc/mycorp.json",
+			},
+		},
+		{
+			providerID: "gcpsm",
+			want:       ProviderConfig{},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.providerID, func(t *testing.T) {
+			actual := providerConfigFromEnv(tc.providerID, environ)
+			if diff := cmp.Diff(tc.want, actual); diff != "" {
+				t.Errorf("providerConfigFromEnv() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProviderConfigBind(t *testing.T) {
+	tt := []struct {
+		name    string
+		config  ProviderConfig
+		wantErr string
+	}{
+		{
+			name:   "known settings",
+			config: ProviderConfig{"region": "eu-west-3", "insecure": "true"},
+		},
+		{
+			name:    "typo",
+			config:  ProviderConfig{"regoin": "eu-west-3"},
+			wantErr: `unknown setting "regoin", did you mean "region"?`,
+		},
+		{
+			name:    "unknown setting",
+			config:  ProviderConfig{"color": "blue"},
+			wantErr: `unknown setting "color", known settings are insecure, region`,
+		},
+		{
+			name:    "invalid boolean",
+			config:  ProviderConfig{"insecure": "maybe"},
+			wantErr: `setting "insecure" must be true or false, not "maybe"`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				region   string
+				insecure bool
+			)
+			err := tc.config.bind(map[string]any{
+				"region":   &region,
+				"insecure": &insecure,
+			})
+
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("bind() returned %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("bind() returned an error: %v", err)
+			}
+			if region != "eu-west-3" || !insecure {
+				t.Errorf("bind() set region=%q insecure=%t, want region=%q insecure=%t", region, insecure, "eu-west-3", true)
+			}
+		})
+	}
+
+	if err := (ProviderConfig{"region": "eu-west-3"}).bind(nil); err == nil {
+		t.Error("bind() returned no error for a provider without settings")
+	}
+}
+
+func TestResolveAllProviderConfig(t *testing.T) {
+	t.Setenv("MURMUR_FOO_REGION", "eu-west-3")
+	t.Setenv("MURMUR_FOO_PROFILE", "staging")
+
+	configs := make(chan ProviderConfig, 1)
+	factories := map[string]ProviderFactory{
+		"foo": func(_ context.Context, config ProviderConfig) (Provider, error) {
+			configs <- config
+			return mock.New(), nil
+		},
+	}
+
+	_, err := ResolveAll(map[string]string{
+		"A": "foo:A",
+	},
+		WithProviders(factories),
+		WithProviderConfig("foo", ProviderConfig{"profile": "production"}),
+	)
+	if err != nil {
+		t.Fatalf("ResolveAll() returned an error: %v", err)
+	}
+
+	// Options take precedence over environment variables.
+	want := ProviderConfig{
+		"region":  "eu-west-3",
+		"profile": "production",
+	}
+	if diff := cmp.Diff(want, <-configs); diff != "" {
+		t.Errorf("provider config mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLocalFileProviderConfigFromEnv(t *testing.T) {
+	t.Setenv("MURMUR_LOCALFILE_TRIM_NEWLINE", "true")
+	t.Setenv("MURMUR_LOCALFILE_STRICT", "true")
+
+	dir := t.TempDir()
+	private := filepath.Join(dir, "private")
+	if err := os.WriteFile(private, []byte("szechuan\n"), 0o600); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+	public := filepath.Join(dir, "public")
+	if err := os.WriteFile(public, []byte("ketchup\n"), 0o600); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+	// The umask may have removed some permissions.
+	if err := os.Chmod(public, 0o644); err != nil {
+		t.Fatalf("could not change file mode: %v", err)
+	}
+
+	actual, err := ResolveAll(map[string]string{"SAUCE": "localfile:" + private})
+	if err != nil {
+		t.Fatalf("ResolveAll() returned an error: %v", err)
+	}
+	if actual["SAUCE"] != "szechuan" {
+		t.Errorf("SAUCE == %q, want %q", actual["SAUCE"], "szechuan")
+	}
+
+	if _, err := ResolveAll(map[string]string{"SAUCE": "localfile:" + public}); err == nil {
+		t.Error("ResolveAll() did not return an error for a world-readable file")
+	}
+}
+
+func TestBuiltinProviderConfig(t *testing.T) {
+	// Built-in providers reject settings they do not know about before doing
+	// anything else, like loading credentials.
+	for id, factory := range ProviderFactories {
+		t.Run(id, func(t *testing.T) {
+			_, err := factory(context.Background(), ProviderConfig{"colour": "blue"})
+			if err == nil || !strings.Contains(err.Error(), `unknown setting "colour"`) {
+				t.Errorf("factory returned %v, want an error about the unknown setting", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package murmur
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// A Phase is a step of resolution during which a variable can fail.
+type Phase string
+
+const (
+	// PhaseParse is when murmur parses a variable's value as a query.
+	PhaseParse Phase = "parse"
+	// PhaseProviderInit is when murmur creates the provider a query uses.
+	PhaseProviderInit Phase = "provider init"
+	// PhaseResolve is when the provider fetches the secret a query references.
+	PhaseResolve Phase = "resolve"
+	// PhaseFilter is when murmur applies a query's filters to the secret.
+	PhaseFilter Phase = "filter"
+)
+
+// A ResolutionError describes why murmur could not resolve a variable.
+type ResolutionError struct {
+	// Variable is the name of the variable.
+	Variable string
+	// Query is the position of the failed query within the variable's value,
+	// starting at 1, if the value embeds queries with the ${query} syntax.
+	// Otherwise, Query is zero.
+	Query int
+	// ProviderID and Ref come from the failed query. They are empty if the
+	// variable failed before murmur could parse its query.
+	ProviderID string
+	Ref        string
+	// Phase is the step of resolution during which the variable failed.
+	Phase Phase
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ResolutionError) Error() string {
+	if e.Query > 0 {
+		return fmt.Sprintf("%s: embedded query %d: %v", e.Variable, e.Query, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Variable, e.Err)
+}
+
+func (e *ResolutionError) Unwrap() error {
+	return e.Err
+}
+
+// ResolutionErrors is the error ResolveAll returns when variables fail. It
+// holds one ResolutionError per failed variable, or per failed query for
+// variables that embed several queries, sorted by variable name.
+//
+// Use errors.As to find out which variables failed:
+//
+//	var resErrs murmur.ResolutionErrors
+//	if errors.As(err, &resErrs) {
+//	    for _, e := range resErrs {
+//	        log.Printf("%s failed during %s", e.Variable, e.Phase)
+//	    }
+//	}
+type ResolutionErrors []*ResolutionError
+
+func (errs ResolutionErrors) Error() string {
+	list := make([]error, len(errs))
+	for i, err := range errs {
+		list[i] = err
+	}
+	return multierror.ListFormatFunc(list)
+}
+
+// Unwrap returns each ResolutionError, so that errors.Is and errors.As find
+// errors from any failed variable.
+func (errs ResolutionErrors) Unwrap() []error {
+	list := make([]error, len(errs))
+	for i, err := range errs {
+		list[i] = err
+	}
+	return list
+}
+
+// sort orders errors by variable name, then by query.
+func (errs ResolutionErrors) sort() {
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Variable != errs[j].Variable {
+			return errs[i].Variable < errs[j].Variable
+		}
+		return errs[i].Query < errs[j].Query
+	})
+}
@@ -1,12 +1,20 @@
 package murmur
 
-import "github.com/busser/murmur/pkg/murmur/filters/jsonpath"
+import (
+	"github.com/busser/murmur/pkg/murmur/filters/base64"
+	"github.com/busser/murmur/pkg/murmur/filters/jsonpath"
+)
 
 // A Filter transforms a value obtained from a secret store into another value
 // based on the given rule.
 type Filter func(value, rule string) (string, error)
 
+// Filters contains a Filter for each filter ID known to murmur. Resolvers use a
+// copy of this map, taken when they are created, unless WithFilters says
+// otherwise.
 var Filters = map[string]Filter{
 	// Kubernetes JSONPath templating.
 	"jsonpath": jsonpath.Filter,
+	// Base64 encoding and decoding.
+	"base64": base64.Filter,
 }
@@ -0,0 +1,43 @@
+package base64
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// Filter encodes the given value to base64 or decodes it from base64,
+// depending on the rule, which must be "encode" or "decode".
+//
+// Encoding uses the standard alphabet, with padding. Decoding accepts both the
+// standard and URL-safe alphabets, with or without padding.
+func Filter(value, rule string) (string, error) {
+	switch rule {
+	case "encode":
+		return base64.StdEncoding.EncodeToString([]byte(value)), nil
+	case "decode":
+		return decode(value)
+	default:
+		return "", fmt.Errorf("invalid rule %q, must be \"encode\" or \"decode\"", rule)
+	}
+}
+
+func decode(value string) (string, error) {
+	// Secrets often end with a newline, which is not part of the encoded data.
+	value = strings.TrimSpace(value)
+
+	encoding := base64.StdEncoding
+	if strings.ContainsAny(value, "-_") {
+		encoding = base64.URLEncoding
+	}
+	if !strings.HasSuffix(value, "=") {
+		encoding = encoding.WithPadding(base64.NoPadding)
+	}
+
+	decoded, err := encoding.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64: %w", err)
+	}
+
+	return string(decoded), nil
+}
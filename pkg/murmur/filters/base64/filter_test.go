@@ -0,0 +1,79 @@
+package base64
+
+import (
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	tt := []struct {
+		name    string
+		value   string
+		rule    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "encode",
+			value: "szechuan sauce",
+			rule:  "encode",
+			want:  "c3plY2h1YW4gc2F1Y2U=",
+		},
+		{
+			name:  "encode empty value",
+			value: "",
+			rule:  "encode",
+			want:  "",
+		},
+		{
+			name:  "decode",
+			value: "c3plY2h1YW4gc2F1Y2U=",
+			rule:  "decode",
+			want:  "szechuan sauce",
+		},
+		{
+			name:  "decode without padding",
+			value: "c3plY2h1YW4gc2F1Y2U",
+			rule:  "decode",
+			want:  "szechuan sauce",
+		},
+		{
+			name:  "decode URL-safe alphabet",
+			value: "-_8=",
+			rule:  "decode",
+			want:  "\xfb\xff",
+		},
+		{
+			name:  "decode with trailing newline",
+			value: "c3plY2h1YW4gc2F1Y2U=\n",
+			rule:  "decode",
+			want:  "szechuan sauce",
+		},
+		{
+			name:    "decode invalid value",
+			value:   "not base64!",
+			rule:    "decode",
+			wantErr: true,
+		},
+		{
+			name:    "invalid rule",
+			value:   "szechuan sauce",
+			rule:    "reverse",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := Filter(tc.value, tc.rule)
+			if err != nil && !tc.wantErr {
+				t.Errorf("Filter() returned an error: %v", err)
+			}
+			if err == nil && tc.wantErr {
+				t.Error("Filter() did not return an error")
+			}
+			if actual != tc.want {
+				t.Errorf("Filter() == %#v, want %#v", actual, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package murmur
+
+import "strings"
+
+// interpolation is a variable's value with queries embedded in a literal
+// string, like "postgres://app:${awssm:db-pass}@${gcpsm:proj/db-host}/app".
+type interpolation struct {
+	// literals surround the queries: literals[i] comes right before
+	// queries[i], and the last literal comes after the last query.
+	literals []string
+	queries  []query
+}
+
+// parseInterpolation finds the queries embedded in s with the ${query} syntax.
+// Only embedded strings for which parse returns true are considered queries;
+// anything else is part of the literal string. Writing $${ produces a literal
+// ${. parseInterpolation reports whether s contains any query at all.
+func parseInterpolation(s string, parse func(string) (query, bool)) (*interpolation, bool) {
+	var (
+		interp  interpolation
+		literal strings.Builder
+	)
+
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "$${") {
+			literal.WriteString("${")
+			i += len("$${")
+			continue
+		}
+
+		if strings.HasPrefix(s[i:], "${") {
+			start := i + len("${")
+			end := closingBrace(s, start)
+			if end >= 0 {
+				if q, ok := parse(s[start:end]); ok {
+					interp.literals = append(interp.literals, literal.String())
+					interp.queries = append(interp.queries, q)
+					literal.Reset()
+					i = end + 1
+					continue
+				}
+			}
+		}
+
+		literal.WriteByte(s[i])
+		i++
+	}
+
+	if len(interp.queries) == 0 {
+		return nil, false
+	}
+
+	interp.literals = append(interp.literals, literal.String())
+
+	return &interp, true
+}
+
+// closingBrace returns the index of the "}" that closes a "{" opened right
+// before s[start], or -1 if there is none. Queries may contain balanced braces,
+// like in "${awssm:db|jsonpath:{.password}}".
+func closingBrace(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// render returns the interpolated string, with values[i] in place of the i-th
+// query.
+func (interp *interpolation) render(values []string) string {
+	var b strings.Builder
+
+	for i, v := range values {
+		b.WriteString(interp.literals[i])
+		b.WriteString(v)
+	}
+	b.WriteString(interp.literals[len(interp.literals)-1])
+
+	return b.String()
+}
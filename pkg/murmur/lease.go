@@ -0,0 +1,116 @@
+package murmur
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Modified during testing to speed up lease renewal.
+var minLeaseRenewalDelay = time.Second
+
+// How long murmur waits for leases to be revoked before giving up.
+const leaseRevocationTimeout = 10 * time.Second
+
+// leaseSet is a list of leases that can be added to concurrently.
+type leaseSet struct {
+	mu     sync.Mutex
+	leases []Lease
+}
+
+func (s *leaseSet) add(leases ...Lease) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.leases = append(s.leases, leases...)
+}
+
+func (s *leaseSet) list() []Lease {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.leases
+}
+
+// keepLeasesAlive renews each lease before it expires, until ctx is done.
+// It returns once all renewal loops have stopped.
+func keepLeasesAlive(ctx context.Context, leases []Lease, logger *slog.Logger) {
+	var wg sync.WaitGroup
+
+	for _, lease := range leases {
+		if !lease.Renewable() {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			keepLeaseAlive(ctx, lease, logger)
+		}()
+	}
+
+	wg.Wait()
+}
+
+// keepLeaseAlive renews the lease when half of its TTL has elapsed, until ctx
+// is done or the lease can no longer be extended.
+func keepLeaseAlive(ctx context.Context, lease Lease, logger *slog.Logger) {
+	ttl := lease.TTL()
+
+	for ttl > 0 {
+		delay := ttl / 2
+		if delay < minLeaseRenewalDelay {
+			delay = minLeaseRenewalDelay
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		newTTL, err := lease.Renew(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// The lease has not expired yet, so we try again sooner.
+			logger.Warn("could not renew lease", "lease", lease.ID(), "error", err)
+			ttl -= delay
+			continue
+		}
+
+		logger.Debug("renewed lease", "lease", lease.ID(), "ttl", newTTL)
+		ttl = newTTL
+	}
+}
+
+// revokeLeases revokes all leases concurrently. Failures are logged, since
+// there is nothing more murmur can do about them.
+func revokeLeases(leases []Lease, logger *slog.Logger) {
+	if len(leases) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), leaseRevocationTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+
+	for _, lease := range leases {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := lease.Revoke(ctx); err != nil {
+				logger.Warn("could not revoke lease", "lease", lease.ID(), "error", err)
+				return
+			}
+			logger.Debug("revoked lease", "lease", lease.ID())
+		}()
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,220 @@
+package murmur
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/busser/murmur/pkg/environ"
+	"github.com/busser/murmur/pkg/murmur/providers/mock"
+)
+
+type fakeLease struct {
+	ttl       time.Duration
+	renewable bool
+
+	mu      sync.Mutex
+	renewed int
+	revoked bool
+}
+
+func (l *fakeLease) ID() string         { return "fake" }
+func (l *fakeLease) TTL() time.Duration { return l.ttl }
+func (l *fakeLease) Renewable() bool    { return l.renewable }
+
+func (l *fakeLease) Renew(ctx context.Context) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.renewed++
+	return l.ttl, nil
+}
+
+func (l *fakeLease) Revoke(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.revoked = true
+	return nil
+}
+
+func (l *fakeLease) state() (renewed int, revoked bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.renewed, l.revoked
+}
+
+// leasingProvider is a mock provider that issues a lease for each secret it
+// resolves. Leases last an hour, unless ttl is set.
+type leasingProvider struct {
+	MockProvider
+	ttl time.Duration
+
+	mu     sync.Mutex
+	leases []Lease
+}
+
+func (p *leasingProvider) Resolve(ctx context.Context, ref string) (string, error) {
+	val, err := p.MockProvider.Resolve(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ttl := p.ttl
+	if ttl == 0 {
+		ttl = time.Hour
+	}
+	p.leases = append(p.leases, &fakeLease{ttl: ttl, renewable: true})
+
+	return val, nil
+}
+
+func (p *leasingProvider) Leases() []Lease {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.leases
+}
+
+func TestKeepLeasesAlive(t *testing.T) {
+	originalDelay := minLeaseRenewalDelay
+	defer func() { minLeaseRenewalDelay = originalDelay }()
+	minLeaseRenewalDelay = time.Millisecond
+
+	renewable := &fakeLease{ttl: 20 * time.Millisecond, renewable: true}
+	notRenewable := &fakeLease{ttl: 20 * time.Millisecond, renewable: false}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	keepLeasesAlive(ctx, []Lease{renewable, notRenewable}, slog.New(slog.DiscardHandler))
+
+	if renewed, _ := renewable.state(); renewed < 2 {
+		t.Errorf("renewable lease renewed %d times, want at least 2", renewed)
+	}
+	if renewed, _ := notRenewable.state(); renewed != 0 {
+		t.Errorf("non-renewable lease renewed %d times, want 0", renewed)
+	}
+
+	revokeLeases([]Lease{renewable, notRenewable}, slog.New(slog.DiscardHandler))
+
+	for _, l := range []*fakeLease{renewable, notRenewable} {
+		if _, revoked := l.state(); !revoked {
+			t.Error("lease not revoked")
+		}
+	}
+}
+
+func TestResolveAllLeases(t *testing.T) {
+	provider := &leasingProvider{MockProvider: mock.New()}
+
+	opts := newOptions([]Option{
+		WithProviders(map[string]ProviderFactory{
+			"lease": func(context.Context, ProviderConfig) (Provider, error) { return provider, nil },
+		}),
+	})
+
+	// Successful resolution returns all leases, without revoking them.
+
+	_, leases, _, err := resolveAll(context.Background(), map[string]string{
+		"A": "lease:A",
+		"B": "lease:B",
+		"C": "lease:A",
+	}, opts)
+	if err != nil {
+		t.Fatalf("resolveAll() returned an error: %v", err)
+	}
+	if len(leases) != 2 {
+		t.Fatalf("resolveAll() returned %d leases, want 2", len(leases))
+	}
+	for _, l := range leases {
+		if _, revoked := l.(*fakeLease).state(); revoked {
+			t.Error("lease revoked after successful resolution")
+		}
+	}
+
+	// Failed resolution revokes all leases obtained along the way.
+
+	provider = &leasingProvider{MockProvider: mock.New()}
+
+	_, leases, _, err = resolveAll(context.Background(), map[string]string{
+		"A": "lease:A",
+		"B": "lease:FAIL",
+	}, opts)
+	if err == nil {
+		t.Fatal("resolveAll() returned no error but it should have")
+	}
+	if leases != nil {
+		t.Errorf("resolveAll() returned %d leases on failure, want none", len(leases))
+	}
+	for _, l := range provider.Leases() {
+		if _, revoked := l.(*fakeLease).state(); !revoked {
+			t.Error("lease not revoked after failed resolution")
+		}
+	}
+}
+
+func TestRunLeases(t *testing.T) {
+	originalDelay := minLeaseRenewalDelay
+	defer func() { minLeaseRenewalDelay = originalDelay }()
+	minLeaseRenewalDelay = time.Millisecond
+
+	// Clear all environment variables for the duration of the test.
+	originalEnv := os.Environ()
+	os.Clearenv()
+	defer func() {
+		os.Clearenv()
+		for k, v := range environ.ToMap(originalEnv) {
+			os.Setenv(k, v)
+		}
+	}()
+	os.Setenv("SECRET_SAUCE", "lease:szechuan")
+
+	newResolver := func(provider *leasingProvider) *Resolver {
+		return NewResolver(WithProviders(map[string]ProviderFactory{
+			"lease": func(context.Context, ProviderConfig) (Provider, error) { return provider, nil },
+		}))
+	}
+
+	// Leases are renewed while the command runs, and revoked once it exits.
+
+	provider := &leasingProvider{MockProvider: mock.New(), ttl: 20 * time.Millisecond}
+
+	exitCode, err := newResolver(provider).Run("/bin/sh", "-c", "sleep 0.2")
+	if err != nil {
+		t.Fatalf("Run() returned an error: %v", err)
+	}
+	if exitCode != 0 {
+		t.Errorf("got exit code %d, want 0", exitCode)
+	}
+	if len(provider.Leases()) != 1 {
+		t.Fatalf("provider issued %d leases, want 1", len(provider.Leases()))
+	}
+	renewed, revoked := provider.Leases()[0].(*fakeLease).state()
+	if renewed < 2 {
+		t.Errorf("lease renewed %d times while the command ran, want at least 2", renewed)
+	}
+	if !revoked {
+		t.Error("lease not revoked after the command exited")
+	}
+
+	// Leases are revoked if the command cannot start.
+
+	provider = &leasingProvider{MockProvider: mock.New()}
+
+	if _, err := newResolver(provider).Run("/does/not/exist"); err == nil {
+		t.Fatal("Run() returned no error but it should have")
+	}
+	if len(provider.Leases()) != 1 {
+		t.Fatalf("provider issued %d leases, want 1", len(provider.Leases()))
+	}
+	if _, revoked := provider.Leases()[0].(*fakeLease).state(); !revoked {
+		t.Error("lease not revoked after the command failed to start")
+	}
+}
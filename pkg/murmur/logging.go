@@ -0,0 +1,58 @@
+package murmur
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Environment variables that configure the loggers NewLogger returns.
+const (
+	// LogLevelEnv sets the minimum level of logs: "debug", "info", "warn", or
+	// "error". It defaults to "info".
+	LogLevelEnv = "MURMUR_LOG_LEVEL"
+	// LogFormatEnv sets the format of logs: "text" or "json". It defaults to
+	// "text".
+	LogFormatEnv = "MURMUR_LOG_FORMAT"
+)
+
+// NewLogger returns a logger that writes to w, with the level and format set
+// by the MURMUR_LOG_LEVEL and MURMUR_LOG_FORMAT environment variables. Murmur
+// logs with such a logger, writing to stderr, unless given another one with
+// WithLogger.
+//
+// At the debug level, murmur logs each step of resolution: providers it
+// initializes, references it resolves, and filters it applies. It never logs
+// the values of secrets.
+func NewLogger(w io.Writer) (*slog.Logger, error) {
+	level := slog.LevelInfo
+	if s := os.Getenv(LogLevelEnv); s != "" {
+		if err := level.UnmarshalText([]byte(s)); err != nil {
+			return nil, fmt.Errorf("invalid %s %q: must be debug, info, warn, or error", LogLevelEnv, s)
+		}
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+
+	switch format := os.Getenv(LogFormatEnv); strings.ToLower(format) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, handlerOpts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, handlerOpts)), nil
+	default:
+		return nil, fmt.Errorf("invalid %s %q: must be text or json", LogFormatEnv, format)
+	}
+}
+
+// defaultLogger returns the logger murmur uses unless given another one. Since
+// it cannot fail, it ignores invalid settings, with a warning.
+func defaultLogger() *slog.Logger {
+	logger, err := NewLogger(os.Stderr)
+	if err != nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+		logger.Warn("ignoring logging settings", "error", err)
+	}
+	return logger
+}
@@ -0,0 +1,140 @@
+package murmur
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/busser/murmur/pkg/murmur/providers/mock"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewLogger(t *testing.T) {
+	tt := []struct {
+		name      string
+		level     string
+		format    string
+		wantDebug bool
+		wantJSON  bool
+		wantErr   bool
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name:      "debug",
+			level:     "debug",
+			wantDebug: true,
+		},
+		{
+			name:      "uppercase",
+			level:     "DEBUG",
+			format:    "JSON",
+			wantDebug: true,
+			wantJSON:  true,
+		},
+		{
+			name:     "json",
+			level:    "warn",
+			format:   "json",
+			wantJSON: true,
+		},
+		{
+			name:    "invalid level",
+			level:   "verbose",
+			wantErr: true,
+		},
+		{
+			name:    "invalid format",
+			format:  "yaml",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(LogLevelEnv, tc.level)
+			t.Setenv(LogFormatEnv, tc.format)
+
+			var buf bytes.Buffer
+			logger, err := NewLogger(&buf)
+			if err != nil {
+				if !tc.wantErr {
+					t.Fatalf("NewLogger() returned an error: %v", err)
+				}
+				return
+			}
+			if tc.wantErr {
+				t.Fatal("NewLogger() did not return an error")
+			}
+
+			logger.Debug("debug message")
+			logger.Error("error message")
+
+			if got := strings.Contains(buf.String(), "debug message"); got != tc.wantDebug {
+				t.Errorf("logged debug message: %t, want %t; logs:\n%s", got, tc.wantDebug, buf.String())
+			}
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if got := json.Valid([]byte(lines[len(lines)-1])); got != tc.wantJSON {
+				t.Errorf("logged JSON: %t, want %t; logs:\n%s", got, tc.wantJSON, buf.String())
+			}
+		})
+	}
+}
+
+func TestResolveAllDebugLogs(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	upper := func(value, rule string) (string, error) {
+		return strings.ToUpper(value), nil
+	}
+
+	_, err := ResolveAll(
+		map[string]string{
+			"PASSWORD": "mock:password|upper:all",
+			"SAME":     "mock:password",
+		},
+		WithProviders(map[string]ProviderFactory{
+			"mock": func(context.Context, ProviderConfig) (Provider, error) { return mock.New(), nil },
+		}),
+		WithFilter("upper", upper),
+		WithLogger(logger),
+	)
+	if err != nil {
+		t.Fatalf("ResolveAll() returned an error: %v", err)
+	}
+
+	var messages []string
+	for line := range strings.Lines(buf.String()) {
+		var entry struct {
+			Msg string `json:"msg"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		messages = append(messages, entry.Msg)
+	}
+
+	want := []string{
+		"initialized provider",
+		"resolved reference",
+		"cache hit",
+		"applied filter",
+	}
+	if diff := cmp.Diff(want, messages); diff != "" {
+		t.Errorf("log messages mismatch (-want +got):\n%s", diff)
+	}
+
+	// Secrets must never end up in logs.
+	for _, secret := range []string{mock.ValueFor("password"), strings.ToUpper(mock.ValueFor("password"))} {
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("logs contain a secret value %q:\n%s", secret, buf.String())
+		}
+	}
+}
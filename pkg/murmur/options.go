@@ -0,0 +1,227 @@
+package murmur
+
+import (
+	"log/slog"
+	"maps"
+	"time"
+)
+
+// QueryPrefix marks a value as a murmur query, like in
+// "murmur+awssm:my-secret". Murmur always accepts the prefix, and requires it
+// in strict mode.
+const QueryPrefix = "murmur+"
+
+// An Option changes how murmur resolves variables.
+type Option func(*options)
+
+type options struct {
+	// Whether only values starting with QueryPrefix are queries.
+	strictPrefix bool
+	// Whether values that look like queries with a typo are errors.
+	failOnUnknown bool
+	// Whether queries embedded in values with the ${query} syntax are
+	// resolved.
+	interpolate bool
+	// Whether Run redacts secrets from the command's output.
+	redact bool
+	// How long resolution may take. Zero means no limit.
+	timeout time.Duration
+	// How to retry resolutions that fail with transient errors.
+	retryPolicy RetryPolicy
+	// How many secrets to fetch at once from each provider. Zero means
+	// concurrency decides.
+	maxConcurrency int
+
+	// The providers, filters, and per-provider concurrency limits murmur
+	// uses. They default to copies of ProviderFactories, Filters, and
+	// ProviderConcurrency.
+	providers   map[string]ProviderFactory
+	filters     map[string]Filter
+	concurrency map[string]int
+
+	// Settings for providers, by provider ID. They take precedence over
+	// settings from environment variables.
+	providerConfigs map[string]ProviderConfig
+
+	// Where murmur logs what it does.
+	logger *slog.Logger
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		retryPolicy: DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.providers == nil {
+		o.providers = maps.Clone(ProviderFactories)
+	}
+	if o.filters == nil {
+		o.filters = maps.Clone(Filters)
+	}
+	if o.concurrency == nil {
+		o.concurrency = maps.Clone(ProviderConcurrency)
+	}
+	if o.logger == nil {
+		o.logger = defaultLogger()
+	}
+
+	return o
+}
+
+// concurrencyFor returns the maximum number of secrets to fetch at once from
+// the provider with the given ID.
+func (o options) concurrencyFor(providerID string) int {
+	if o.maxConcurrency > 0 {
+		return o.maxConcurrency
+	}
+	if n, ok := o.concurrency[providerID]; ok && n > 0 {
+		return n
+	}
+	return DefaultProviderConcurrency
+}
+
+// WithStrictPrefix makes murmur only resolve queries that start with
+// QueryPrefix, like "murmur+awssm:my-secret". Other values are left as-is, even
+// if they look like queries. This prevents murmur from overloading variables
+// whose value happens to start with a provider's ID, like "op:billing".
+func WithStrictPrefix() Option {
+	return func(o *options) {
+		o.strictPrefix = true
+	}
+}
+
+// WithFailOnUnknown makes murmur fail on values that look like queries with a
+// typo in their provider or filter, like "awsm:my-secret" or
+// "awssm:my-secret|jsonpth:{.password}". By default, murmur leaves these values
+// as-is and logs a warning.
+func WithFailOnUnknown() Option {
+	return func(o *options) {
+		o.failOnUnknown = true
+	}
+}
+
+// WithInterpolation makes murmur resolve queries embedded in values with the
+// ${query} syntax, like "postgres://app:${awssm:db-pass}@db/app". By default,
+// murmur leaves such values as-is, since they may use ${...} for something
+// else, like shell parameter expansion in "${vault:-/tmp}".
+func WithInterpolation() Option {
+	return func(o *options) {
+		o.interpolate = true
+	}
+}
+
+// WithRedaction makes Run replace secrets in the output of the command it runs
+// with "***", so that they do not end up in logs. It redacts the values of
+// secrets, before and after filtering, and their base64, URL, and JSON
+// encodings. Values shorter than 4 bytes are not redacted, since they are too
+// likely to appear in the output by chance.
+//
+// The command's stdout and stderr are then pipes instead of murmur's own, so
+// the command may behave as it does when its output is not a terminal, and
+// lines it writes to both at nearly the same time may come out of order.
+func WithRedaction() Option {
+	return func(o *options) {
+		o.redact = true
+	}
+}
+
+// WithTimeout makes murmur give up on resolving secrets after the given
+// duration. The error murmur returns then lists the variables that were still
+// pending.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithRetryPolicy changes how murmur retries resolutions that fail with
+// transient errors. By default, murmur uses DefaultRetryPolicy.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(o *options) {
+		o.retryPolicy = p
+	}
+}
+
+// WithMaxConcurrency limits how many secrets murmur fetches at once from each
+// provider, regardless of per-provider limits. Values below 1 restore the
+// per-provider limits.
+func WithMaxConcurrency(n int) Option {
+	return func(o *options) {
+		o.maxConcurrency = n
+	}
+}
+
+// WithProviders makes murmur use the given providers instead of
+// ProviderFactories. Murmur only resolves queries for these providers.
+func WithProviders(factories map[string]ProviderFactory) Option {
+	return func(o *options) {
+		o.providers = maps.Clone(factories)
+	}
+}
+
+// WithProvider adds a provider to those murmur uses, or replaces the provider
+// with the same ID.
+func WithProvider(id string, factory ProviderFactory) Option {
+	return func(o *options) {
+		if o.providers == nil {
+			o.providers = maps.Clone(ProviderFactories)
+		}
+		o.providers[id] = factory
+	}
+}
+
+// WithFilters makes murmur use the given filters instead of Filters.
+func WithFilters(filters map[string]Filter) Option {
+	return func(o *options) {
+		o.filters = maps.Clone(filters)
+	}
+}
+
+// WithFilter adds a filter to those murmur uses, or replaces the filter with
+// the same ID.
+func WithFilter(id string, filter Filter) Option {
+	return func(o *options) {
+		if o.filters == nil {
+			o.filters = maps.Clone(Filters)
+		}
+		o.filters[id] = filter
+	}
+}
+
+// WithProviderConcurrency limits how many secrets murmur fetches at once from
+// the provider with the given ID, instead of the limit in ProviderConcurrency.
+// WithMaxConcurrency takes precedence.
+func WithProviderConcurrency(id string, n int) Option {
+	return func(o *options) {
+		if o.concurrency == nil {
+			o.concurrency = maps.Clone(ProviderConcurrency)
+		}
+		o.concurrency[id] = n
+	}
+}
+
+// WithLogger makes murmur log to the given logger instead of one configured by
+// environment variables, as described in NewLogger.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithProviderConfig sets settings for the provider with the given ID, like
+// its region or endpoint. These take precedence over settings from environment
+// variables like MURMUR_AWSSM_REGION. See ProviderConfig.
+func WithProviderConfig(id string, config ProviderConfig) Option {
+	return func(o *options) {
+		if o.providerConfigs == nil {
+			o.providerConfigs = make(map[string]ProviderConfig)
+		}
+		if o.providerConfigs[id] == nil {
+			o.providerConfigs[id] = make(ProviderConfig)
+		}
+		maps.Copy(o.providerConfigs[id], config)
+	}
+}
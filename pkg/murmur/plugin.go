@@ -0,0 +1,76 @@
+package murmur
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"sort"
+	"strings"
+
+	"github.com/busser/murmur/pkg/murmur/providers/plugin"
+)
+
+// PluginProviders returns the given providers, plus a ProviderFactory for each
+// provider plugin found by plugin.Discover. This lets murmur resolve queries
+// for secret stores it has no built-in support for. Pass the result to
+// WithProviders:
+//
+//	providers, err := murmur.PluginProviders(murmur.ProviderFactories, logger)
+//	if err != nil {
+//		return err
+//	}
+//	r := murmur.NewResolver(murmur.WithProviders(providers))
+//
+// The given providers take precedence over plugins with the same ID. The given
+// map is not modified. Plugins found are logged to logger, or to the logger
+// murmur uses by default if logger is nil.
+func PluginProviders(providers map[string]ProviderFactory, logger *slog.Logger) (map[string]ProviderFactory, error) {
+	plugins, err := plugin.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(plugins))
+	for id := range plugins {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	factories := maps.Clone(providers)
+	if factories == nil {
+		factories = make(map[string]ProviderFactory)
+	}
+
+	if logger == nil {
+		logger = defaultLogger()
+	}
+	for _, id := range ids {
+		if _, exists := factories[id]; exists {
+			logger.Warn("ignoring plugin, provider already exists", "provider", id, "plugin", plugins[id])
+			continue
+		}
+
+		path := plugins[id]
+		factories[id] = pluginFactory(id, path)
+		logger.Debug("registered plugin", "provider", id, "plugin", path)
+	}
+
+	return factories, nil
+}
+
+// pluginFactory returns a ProviderFactory for the plugin at path. Plugins get
+// their settings from environment variables, the same way murmur does, so
+// settings from options are passed to them as environment variables.
+func pluginFactory(id, path string) ProviderFactory {
+	return func(_ context.Context, config ProviderConfig) (Provider, error) {
+		prefix := providerConfigEnvPrefix(id)
+
+		var env []string
+		for name, value := range config {
+			env = append(env, prefix+strings.ToUpper(name)+"="+value)
+		}
+		sort.Strings(env)
+
+		return plugin.NewWithOptions(path, plugin.Options{Env: env})
+	}
+}
@@ -0,0 +1,97 @@
+package murmur
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// A minimal plugin, written as a shell script. It has a single setting, which
+// adds a suffix to values.
+const shellPlugin = `#!/bin/sh
+while IFS= read -r line; do
+	id=$(echo "$line" | sed 's/.*"id":\([0-9]*\).*/\1/')
+	ref=$(echo "$line" | sed 's/.*"ref":"\([^"]*\)".*/\1/')
+	echo "{\"id\":$id,\"value\":\"plugin-$ref$MURMUR_MYCORP_SUFFIX\"}"
+done
+`
+
+func TestPluginProviders(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script plugins require a Unix system")
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"murmur-provider-mycorp", "murmur-provider-passthrough"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(shellPlugin), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir+string(filepath.ListSeparator)+os.Getenv("PATH"))
+	t.Setenv("MURMUR_PROVIDER_PLUGINS", "")
+
+	builtin := map[string]ProviderFactory{
+		"passthrough": ProviderFactories["passthrough"],
+	}
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	providers, err := PluginProviders(builtin, logger)
+	if err != nil {
+		t.Fatalf("PluginProviders() returned an error: %v", err)
+	}
+	if len(builtin) != 1 {
+		t.Errorf("PluginProviders() modified the given providers")
+	}
+	for _, want := range []string{
+		`msg="registered plugin" provider=mycorp`,
+		`msg="ignoring plugin, provider already exists" provider=passthrough`,
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logs do not contain %q:\n%s", want, logs.String())
+		}
+	}
+
+	actual, err := ResolveAll(map[string]string{
+		"A": "mycorp:foo",
+		"B": "mycorp:bar",
+		"C": "passthrough:baz",
+		"D": "unknown:qux",
+	}, WithProviders(providers))
+	if err != nil {
+		t.Fatalf("ResolveAll() returned an error: %v", err)
+	}
+
+	want := map[string]string{
+		"A": "plugin-foo",
+		"B": "plugin-bar",
+		// Built-in providers take precedence over plugins.
+		"C": "baz",
+		"D": "unknown:qux",
+	}
+	if diff := cmp.Diff(want, actual); diff != "" {
+		t.Errorf("ResolveAll() mismatch (-want +got):\n%s", diff)
+	}
+	// Plugins receive their settings as environment variables.
+
+	actual, err = ResolveAll(map[string]string{
+		"A": "mycorp:foo",
+	}, WithProviders(providers), WithProviderConfig("mycorp", ProviderConfig{"suffix": "-custom"}))
+	if err != nil {
+		t.Fatalf("ResolveAll() returned an error: %v", err)
+	}
+
+	want = map[string]string{
+		"A": "plugin-foo-custom",
+	}
+	if diff := cmp.Diff(want, actual); diff != "" {
+		t.Errorf("ResolveAll() with settings mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -3,11 +3,10 @@ package murmur
 import (
 	"context"
 
-	"github.com/busser/murmur/pkg/murmur/providers/awssm"
-	"github.com/busser/murmur/pkg/murmur/providers/azkv"
-	"github.com/busser/murmur/pkg/murmur/providers/gcpsm"
+	"github.com/busser/murmur/pkg/murmur/providers"
+	"github.com/busser/murmur/pkg/murmur/providers/k8s"
 	"github.com/busser/murmur/pkg/murmur/providers/passthrough"
-	"github.com/busser/murmur/pkg/murmur/providers/scwsm"
+	"github.com/busser/murmur/pkg/murmur/providers/sops"
 )
 
 // Provider fetches values from a secret store (e.g., AWS Secrets Manager, Azure Key Vault).
@@ -20,7 +19,7 @@ import (
 //
 // Example usage:
 //
-//	provider, err := awssm.New()
+//	provider, err := awssm.New(ctx)
 //	if err != nil {
 //	    return err
 //	}
@@ -43,28 +42,126 @@ type Provider interface {
 	Close() error
 }
 
+// ErrNotFound means that the secret a reference points to does not exist. See
+// providers.ErrNotFound.
+var ErrNotFound = providers.ErrNotFound
+
+// ErrNotBatched means that a batch provider did not fetch a secret with the
+// rest of its batch. See providers.ErrNotBatched.
+var ErrNotBatched = providers.ErrNotBatched
+
+// A Lease grants access to a secret for a limited time. See providers.Lease.
+type Lease = providers.Lease
+
+// LeasingProvider is implemented by providers whose secrets come with leases,
+// like Vault's dynamic secrets. When a provider implements LeasingProvider,
+// murmur renews the leases of the secrets it resolved for as long as the
+// process it runs is alive, and revokes them once the process exits.
+type LeasingProvider interface {
+	Provider
+
+	// Leases returns the leases of all secrets the provider resolved.
+	Leases() []Lease
+}
+
+// BatchProvider is implemented by providers whose secret stores can return
+// several secrets in a single request, like AWS Secrets Manager. When a provider
+// implements BatchProvider, murmur hands it all the unique references it needs
+// at once, instead of calling Resolve for each of them.
+type BatchProvider interface {
+	Provider
+
+	// ResolveBatch returns the values of the secrets with the given refs, in the
+	// same order. If the i-th secret could not be resolved, errs[i] says why
+	// and values[i] is empty. Both slices must have the same length as refs.
+	// ResolveBatch may split refs into as many requests as the secret store
+	// requires. If it cannot fetch some secrets in a batch, it sets their error
+	// to ErrNotBatched, and murmur resolves them with Resolve instead.
+	ResolveBatch(ctx context.Context, refs []string) (values []string, errs []error)
+}
+
 // ProviderFactory creates a new Provider instance.
 // Each call should return a fresh provider with its own resources.
-type ProviderFactory func() (Provider, error)
+// The context only bounds the provider's creation, like loading credentials or
+// logging in; the provider must not use it afterwards.
+// The config holds the provider's settings, like its region or endpoint.
+// Factories should fail on settings they do not know about.
+type ProviderFactory func(ctx context.Context, config ProviderConfig) (Provider, error)
 
 // ProviderFactories contains a ProviderFactory for each provider prefix known to murmur.
 // This map is used by the resolution pipeline to create providers on-demand.
+// Resolvers use a copy of this map, taken when they are created, unless
+// WithProviders says otherwise.
 //
 // Available providers:
 //   - "awssm": AWS Secrets Manager
+//   - "awsps": AWS Systems Manager Parameter Store
 //   - "azkv": Azure Key Vault
 //   - "gcpsm": GCP Secret Manager  
 //   - "scwsm": Scaleway Secret Manager
+//   - "vault": HashiCorp Vault
+//   - "op": 1Password Connect
+//   - "doppler": Doppler
+//   - "k8s": Kubernetes Secrets
+//   - "k8scm": Kubernetes ConfigMaps
+//   - "localfile": Local files
+//   - "sops": SOPS-encrypted files
 //   - "passthrough": Testing/no-op provider
 var ProviderFactories = map[string]ProviderFactory{
 	// Passthrough
-	"passthrough": func() (Provider, error) { return passthrough.New() },
+	"passthrough": withoutConfig(passthrough.New),
 	// Azure Key Vault
-	"azkv": func() (Provider, error) { return azkv.New() },
+	"azkv": newAzureKeyVault,
 	// Google Cloud Secret Manager
-	"gcpsm": func() (Provider, error) { return gcpsm.New() },
+	"gcpsm": newGCPSecretManager,
 	// AWS Secrets Manager
-	"awssm": func() (Provider, error) { return awssm.New() },
+	"awssm": newAWSSecretsManager,
+	// AWS Systems Manager Parameter Store
+	"awsps": newAWSParameterStore,
 	// Scaleway Secret Manager
-	"scwsm": func() (Provider, error) { return scwsm.New() },
+	"scwsm": newScalewaySecretManager,
+	// HashiCorp Vault
+	"vault": newVault,
+	// 1Password Connect
+	"op": newOnePasswordConnect,
+	// Doppler
+	"doppler": newDoppler,
+	// Kubernetes Secrets
+	"k8s": withoutConfig(k8s.New),
+	// Kubernetes ConfigMaps
+	"k8scm": withoutConfig(k8s.NewConfigMaps),
+	// Local files
+	"localfile": newLocalFile,
+	// SOPS-encrypted files
+	"sops": withoutConfig(sops.New),
+}
+
+// DefaultProviderConcurrency is the maximum number of secrets murmur fetches at
+// once from providers missing from ProviderConcurrency, like plugins.
+const DefaultProviderConcurrency = 8
+
+// ProviderConcurrency contains the maximum number of secrets murmur fetches at
+// once from each provider. Limits keep murmur from tripping the rate limits of
+// secret stores when a process has many secrets. WithMaxConcurrency overrides
+// these limits.
+var ProviderConcurrency = map[string]int{
+	// Secrets Manager allows thousands of requests per second, but shares its
+	// quota with everything else running in the account.
+	"awssm": 10,
+	// Parameter Store's default throughput is 40 requests per second.
+	"awsps": 5,
+	// Key Vault allows 4000 requests per 10 seconds, per vault.
+	"azkv":  10,
+	"gcpsm": 10,
+	"scwsm": 5,
+	"vault": 10,
+	"op":    5,
+	// Doppler's API allows a few hundred requests per minute.
+	"doppler":   4,
+	"k8s":       10,
+	"k8scm":     10,
+	"localfile": 16,
+	// Decrypting SOPS files can use a lot of CPU and memory.
+	"sops":        4,
+	"passthrough": 16,
 }
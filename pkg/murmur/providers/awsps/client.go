@@ -0,0 +1,142 @@
+package awsps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/busser/murmur/pkg/murmur/providers"
+)
+
+type client struct {
+	awsClient *ssm.Client
+
+	// Whether SecureString parameters are decrypted before being returned.
+	withDecryption bool
+}
+
+// Options configure a client. The zero value uses the environment's default
+// AWS configuration.
+type Options struct {
+	// Region overrides the AWS region, like "eu-west-3".
+	Region string
+	// Endpoint overrides the URL of the Systems Manager API, for example to use a
+	// VPC endpoint or a local emulator.
+	Endpoint string
+	// Profile selects a profile from the shared AWS config and credentials
+	// files.
+	Profile string
+	// DisableDecryption makes the client return SecureString parameters
+	// encrypted, as Parameter Store stores them, instead of decrypting them.
+	DisableDecryption bool
+	// InsecureSkipVerify disables verification of the API's TLS certificate,
+	// for local emulators that serve self-signed certificates. It has no effect
+	// if HTTPClient is set.
+	InsecureSkipVerify bool
+	// HTTPClient sends requests to the API.
+	HTTPClient *http.Client
+}
+
+// New returns a client that fetches parameters from AWS Systems Manager
+// Parameter Store. SecureString parameters are decrypted, unless
+// DisableDecryption is set.
+func New(ctx context.Context) (*client, error) {
+	return NewWithOptions(ctx, Options{})
+}
+
+// NewWithOptions works like New, but configures the client with the given
+// options.
+func NewWithOptions(ctx context.Context, opts Options) (*client, error) {
+	loadOpts := []func(*config.LoadOptions) error{
+		// Murmur retries transient errors itself. Retrying in the SDK as well
+		// would multiply the number of attempts.
+		config.WithRetryMaxAttempts(1),
+	}
+	if opts.Region != "" {
+		loadOpts = append(loadOpts, config.WithRegion(opts.Region))
+	}
+	if opts.Profile != "" {
+		loadOpts = append(loadOpts, config.WithSharedConfigProfile(opts.Profile))
+	}
+	switch {
+	case opts.HTTPClient != nil:
+		loadOpts = append(loadOpts, config.WithHTTPClient(opts.HTTPClient))
+	case opts.InsecureSkipVerify:
+		loadOpts = append(loadOpts, config.WithHTTPClient(providers.InsecureAWSHTTPClient()))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	c := ssm.NewFromConfig(cfg, func(o *ssm.Options) {
+		if opts.Endpoint != "" {
+			o.BaseEndpoint = aws.String(opts.Endpoint)
+		}
+	})
+
+	return &client{
+		awsClient:      c,
+		withDecryption: !opts.DisableDecryption,
+	}, nil
+}
+
+func (c *client) Resolve(ctx context.Context, ref string) (string, error) {
+	name, err := parseRef(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid reference: %w", err)
+	}
+
+	req := &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(c.withDecryption),
+	}
+
+	resp, err := c.awsClient.GetParameter(ctx, req)
+	if err != nil {
+		err = fmt.Errorf("failed to get parameter %q: %w", name, err)
+		var (
+			paramNotFound   *types.ParameterNotFound
+			versionNotFound *types.ParameterVersionNotFound
+		)
+		if errors.As(err, &paramNotFound) || errors.As(err, &versionNotFound) {
+			return "", providers.NotFound(err)
+		}
+		if isTransient(err) {
+			return "", providers.Transient(err)
+		}
+		return "", err
+	}
+
+	return aws.ToString(resp.Parameter.Value), nil
+}
+
+func (c *client) Close() error {
+	// The client does not need to close its underlying AWS client.
+	return nil
+}
+
+// isTransient reports whether err is a throttling, server, or connection error,
+// which the AWS SDK would retry if its retries were enabled.
+func isTransient(err error) bool {
+	return retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary
+}
+
+// parseRef returns the name of the parameter to fetch. Parameter Store handles
+// version and label selectors itself, as long as they are appended to the
+// parameter's name, like "name:3" or "name:my-label", so the reference is
+// passed along as-is.
+func parseRef(ref string) (name string, err error) {
+	if ref == "" {
+		return "", errors.New("parameter name cannot be empty")
+	}
+
+	return ref, nil
+}
@@ -0,0 +1,24 @@
+package awsps_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/busser/murmur/pkg/murmur/providers/awsps"
+)
+
+func Example() {
+	c, err := awsps.New(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ref := "/murmur/secret-sauce"
+	val, err := c.Resolve(context.Background(), ref)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("The secret sauce is", val)
+}
@@ -0,0 +1,95 @@
+//go:build e2e
+
+package awsps_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/busser/murmur/pkg/murmur/providers/awsps"
+)
+
+func TestClient(t *testing.T) {
+
+	// The parameters this test reads were created with Terraform. The code is
+	// in the terraform/layers/aws-secrets-manager directory of this repository.
+
+	client, err := awsps.New(context.Background())
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+
+	tt := []struct {
+		ref     string
+		wantVal string
+		wantErr bool
+	}{
+		// SecureString parameters.
+		{
+			ref:     "/murmur/secret-sauce",
+			wantVal: "szechuan",
+			wantErr: false,
+		},
+		{
+			ref:     "/murmur/secret-sauce:1",
+			wantVal: "szechuan",
+			wantErr: false,
+		},
+		{
+			ref:     "/murmur/secret-sauce:9999",
+			wantVal: "",
+			wantErr: true,
+		},
+		{
+			ref:     "/murmur/secret-sauce:does-not-exist",
+			wantVal: "",
+			wantErr: true,
+		},
+
+		// String parameters.
+		{
+			ref:     "/murmur/plain-sauce",
+			wantVal: "ketchup",
+			wantErr: false,
+		},
+
+		// Missing parameters.
+		{
+			ref:     "/murmur/does-not-exist",
+			wantVal: "",
+			wantErr: true,
+		},
+	}
+
+	// Test cases are grouped such that they run in parallel and we can perform
+	// cleanup once they are done.
+	t.Run("group", func(t *testing.T) {
+
+		for _, tc := range tt {
+			tc := tc // capture range variable
+			t.Run(tc.ref, func(t *testing.T) {
+				t.Parallel()
+
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+
+				actualVal, err := client.Resolve(ctx, tc.ref)
+				if err != nil && !tc.wantErr {
+					t.Errorf("Resolve() returned an error: %v", err)
+				}
+				if err == nil && tc.wantErr {
+					t.Error("Resolve() did not return an error")
+				}
+				if actualVal != tc.wantVal {
+					t.Errorf("Resolve() == %#v, want %#v", actualVal, tc.wantVal)
+				}
+			})
+		}
+
+	})
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() returned an error: %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package awsps_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/busser/murmur/pkg/murmur/providers/awsps"
+)
+
+// fakeParameterStore serves the parts of the Systems Manager API the client
+// uses.
+type fakeParameterStore struct {
+	// Versions of parameters, by name, oldest first.
+	parameters map[string][]fakeParameter
+}
+
+type fakeParameter struct {
+	value  string
+	secure bool
+}
+
+func (f *fakeParameterStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if target := r.Header.Get("X-Amz-Target"); target != "AmazonSSM.GetParameter" {
+		http.Error(w, "unexpected call to "+target, http.StatusNotImplemented)
+		return
+	}
+
+	var req struct {
+		Name           string
+		WithDecryption bool
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+
+	fail := func(errType string) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"__type": errType})
+	}
+
+	name, selector, _ := strings.Cut(req.Name, ":")
+	versions, ok := f.parameters[name]
+	if !ok {
+		fail("ParameterNotFound")
+		return
+	}
+	version := len(versions)
+	if selector != "" {
+		// Our fake has no labels, only version numbers.
+		n, err := strconv.Atoi(selector)
+		if err != nil || n < 1 || n > len(versions) {
+			fail("ParameterVersionNotFound")
+			return
+		}
+		version = n
+	}
+
+	param := versions[version-1]
+	paramType, value := "String", param.value
+	if param.secure {
+		paramType = "SecureString"
+		if !req.WithDecryption {
+			value = "encrypted:" + value
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"Parameter": map[string]any{
+			"Name":    name,
+			"Type":    paramType,
+			"Value":   value,
+			"Version": version,
+		},
+	})
+}
+
+// TestClientEmulated runs the same tests as TestClient, against a fake
+// Systems Manager API served over TLS with a self-signed certificate instead of
+// AWS, so that it needs neither network access nor credentials.
+func TestClientEmulated(t *testing.T) {
+	// The fake mirrors the parameters that Terraform creates for TestClient.
+	fake := &fakeParameterStore{
+		parameters: map[string][]fakeParameter{
+			"/murmur/secret-sauce": {{value: "szechuan", secure: true}},
+			"/murmur/plain-sauce":  {{value: "ketchup"}},
+		},
+	}
+	srv := httptest.NewTLSServer(fake)
+	t.Cleanup(srv.Close)
+
+	// The API needs requests to be signed, with any credentials.
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_PROFILE", "")
+
+	client, err := awsps.NewWithOptions(context.Background(), awsps.Options{
+		Region:             "eu-west-3",
+		Endpoint:           srv.URL,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		t.Fatalf("NewWithOptions() returned an error: %v", err)
+	}
+
+	tt := []struct {
+		ref     string
+		wantVal string
+		wantErr bool
+	}{
+		// SecureString parameters.
+		{
+			ref:     "/murmur/secret-sauce",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "/murmur/secret-sauce:1",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "/murmur/secret-sauce:9999",
+			wantErr: true,
+		},
+		{
+			ref:     "/murmur/secret-sauce:does-not-exist",
+			wantErr: true,
+		},
+
+		// String parameters.
+		{
+			ref:     "/murmur/plain-sauce",
+			wantVal: "ketchup",
+		},
+
+		// Missing parameters.
+		{
+			ref:     "/murmur/does-not-exist",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.ref, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			actualVal, err := client.Resolve(ctx, tc.ref)
+			if err != nil && !tc.wantErr {
+				t.Errorf("Resolve() returned an error: %v", err)
+			}
+			if err == nil && tc.wantErr {
+				t.Error("Resolve() did not return an error")
+			}
+			if actualVal != tc.wantVal {
+				t.Errorf("Resolve() == %#v, want %#v", actualVal, tc.wantVal)
+			}
+		})
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() returned an error: %v", err)
+	}
+}
+
+func TestClientEmulatedWithoutDecryption(t *testing.T) {
+	fake := &fakeParameterStore{
+		parameters: map[string][]fakeParameter{
+			"/murmur/secret-sauce": {{value: "szechuan", secure: true}},
+			"/murmur/plain-sauce":  {{value: "ketchup"}},
+		},
+	}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_PROFILE", "")
+
+	client, err := awsps.NewWithOptions(context.Background(), awsps.Options{
+		Region:            "eu-west-3",
+		Endpoint:          srv.URL,
+		DisableDecryption: true,
+	})
+	if err != nil {
+		t.Fatalf("NewWithOptions() returned an error: %v", err)
+	}
+	defer client.Close()
+
+	want := map[string]string{
+		"/murmur/secret-sauce": "encrypted:szechuan",
+		"/murmur/plain-sauce":  "ketchup",
+	}
+	for ref, wantVal := range want {
+		actualVal, err := client.Resolve(context.Background(), ref)
+		if err != nil {
+			t.Errorf("Resolve(%q) returned an error: %v", ref, err)
+		}
+		if actualVal != wantVal {
+			t.Errorf("Resolve(%q) == %#v, want %#v", ref, actualVal, wantVal)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package awsps
+
+import "testing"
+
+func TestParseRef(t *testing.T) {
+	tt := []struct {
+		ref      string
+		wantName string
+		wantErr  bool
+	}{
+		{
+			ref:      "/murmur/secret-sauce",
+			wantName: "/murmur/secret-sauce",
+		},
+		{
+			ref:      "/murmur/secret-sauce:3",
+			wantName: "/murmur/secret-sauce:3",
+		},
+		{
+			ref:      "/murmur/secret-sauce:production",
+			wantName: "/murmur/secret-sauce:production",
+		},
+		{
+			ref:     "",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.ref, func(t *testing.T) {
+			name, err := parseRef(tc.ref)
+			if err != nil && !tc.wantErr {
+				t.Errorf("parseRef() returned an error: %v", err)
+			}
+			if err == nil && tc.wantErr {
+				t.Error("parseRef() did not return an error")
+			}
+			if name != tc.wantName {
+				t.Errorf("parseRef() == %#v, want %#v", name, tc.wantName)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package awssm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/busser/murmur/pkg/murmur/providers"
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeSecretsManager serves the parts of the Secrets Manager API the client
+// uses, and records the calls it receives.
+type fakeSecretsManager struct {
+	secrets map[string]string // current values, by name
+	// Versions of secrets, by name. Current versions may be listed here too, to
+	// give them an ID or more stages.
+	versions map[string][]fakeVersion
+	// Whether callers lack the secretsmanager:BatchGetSecretValue permission.
+	denyBatch bool
+
+	mu    sync.Mutex
+	calls []string
+}
+
+type fakeVersion struct {
+	id     string
+	stages []string
+	value  string
+}
+
+func (f *fakeSecretsManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SecretId     string
+		SecretIdList []string
+		VersionId    string
+		VersionStage string
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	target := r.Header.Get("X-Amz-Target")
+	f.mu.Lock()
+	f.calls = append(f.calls, target)
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+
+	switch target {
+	case "secretsmanager.GetSecretValue":
+		name := req.SecretId
+		if n, ok := nameFromARN(req.SecretId); ok {
+			name = n
+		}
+		value, ok := f.value(name, req.VersionId, req.VersionStage)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{
+				"__type":  "ResourceNotFoundException",
+				"message": "Secrets Manager can't find the specified secret.",
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"Name":         name,
+			"ARN":          arn(name),
+			"SecretString": value,
+		})
+
+	case "secretsmanager.BatchGetSecretValue":
+		if f.denyBatch {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{
+				"__type":  "AccessDeniedException",
+				"message": "not authorized to perform: secretsmanager:BatchGetSecretValue",
+			})
+			return
+		}
+		if len(req.SecretIdList) > maxBatchSize {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{
+				"__type":  "InvalidParameterException",
+				"message": "too many secrets",
+			})
+			return
+		}
+
+		var (
+			values  []map[string]any
+			apiErrs []map[string]string
+		)
+		for _, id := range req.SecretIdList {
+			name := id
+			if n, ok := nameFromARN(id); ok {
+				name = n
+			}
+			value, ok := f.secrets[name]
+			if !ok {
+				apiErrs = append(apiErrs, map[string]string{
+					"SecretId":  id,
+					"ErrorCode": "ResourceNotFoundException",
+					"Message":   "Secrets Manager can't find the specified secret.",
+				})
+				continue
+			}
+			values = append(values, map[string]any{
+				"Name":         name,
+				"ARN":          arn(name),
+				"SecretString": value,
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"SecretValues": values,
+			"Errors":       apiErrs,
+		})
+
+	default:
+		http.Error(w, "unexpected call", http.StatusNotImplemented)
+	}
+}
+
+// value returns the value of a secret's version, chosen by ID or by stage. An
+// empty ID and stage mean the current version.
+func (f *fakeSecretsManager) value(name, versionID, versionStage string) (string, bool) {
+	if versionID == "" && (versionStage == "" || versionStage == "AWSCURRENT") {
+		if value, ok := f.secrets[name]; ok {
+			return value, true
+		}
+	}
+
+	for _, v := range f.versions[name] {
+		if versionID != "" && v.id == versionID {
+			return v.value, true
+		}
+		if versionStage != "" && slices.Contains(v.stages, versionStage) {
+			return v.value, true
+		}
+	}
+
+	return "", false
+}
+
+func arn(name string) string {
+	return "arn:aws:secretsmanager:eu-west-3:123456789012:secret:" + name + "-AbCdEf"
+}
+
+func nameFromARN(s string) (string, bool) {
+	const prefix = "arn:aws:secretsmanager:eu-west-3:123456789012:secret:"
+	if len(s) <= len(prefix)+len("-AbCdEf") || s[:len(prefix)] != prefix {
+		return "", false
+	}
+	return s[len(prefix) : len(s)-len("-AbCdEf")], true
+}
+
+// newFakeClient returns a client that talks to fake instead of AWS.
+func newFakeClient(t *testing.T, fake *fakeSecretsManager) *client {
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	return &client{
+		awsClient: secretsmanager.New(secretsmanager.Options{
+			Region:           "eu-west-3",
+			BaseEndpoint:     aws.String(srv.URL),
+			Credentials:      aws.AnonymousCredentials{},
+			RetryMaxAttempts: 1,
+		}),
+	}
+}
+
+func TestResolveBatch(t *testing.T) {
+	fake := &fakeSecretsManager{
+		secrets: make(map[string]string),
+	}
+	for i := range 25 {
+		name := "secret-" + string(rune('a'+i))
+		fake.secrets[name] = "value of " + name
+	}
+
+	c := newFakeClient(t, fake)
+
+	var (
+		refs []string
+		want []string
+	)
+	for i := range 25 {
+		name := "secret-" + string(rune('a'+i))
+		refs = append(refs, name)
+		want = append(want, "value of "+name)
+	}
+	refs = append(refs,
+		"secret-a#AWSCURRENT",
+		arn("secret-b"),
+		"does-not-exist",
+		"secret-c#AWSPREVIOUS",
+	)
+	want = append(want, "value of secret-a", "value of secret-b", "", "")
+
+	values, errs := c.ResolveBatch(context.Background(), refs)
+
+	if diff := cmp.Diff(want, values); diff != "" {
+		t.Errorf("ResolveBatch() values mismatch (-want +got):\n%s", diff)
+	}
+
+	for i, err := range errs {
+		switch refs[i] {
+		case "does-not-exist":
+			if !errors.Is(err, providers.ErrNotFound) {
+				t.Errorf("ResolveBatch() error for %q = %v, want a not-found error", refs[i], err)
+			}
+		case "secret-c#AWSPREVIOUS":
+			if !errors.Is(err, providers.ErrNotBatched) {
+				t.Errorf("ResolveBatch() error for %q = %v, want ErrNotBatched", refs[i], err)
+			}
+		default:
+			if err != nil {
+				t.Errorf("ResolveBatch() error for %q = %v", refs[i], err)
+			}
+		}
+	}
+
+	wantCalls := []string{
+		"secretsmanager.BatchGetSecretValue",
+		"secretsmanager.BatchGetSecretValue",
+	}
+	if diff := cmp.Diff(wantCalls, fake.calls); diff != "" {
+		t.Errorf("API calls mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestResolveBatchWithoutPermission(t *testing.T) {
+	fake := &fakeSecretsManager{
+		secrets: map[string]string{
+			"secret-sauce": "szechuan",
+		},
+		denyBatch: true,
+	}
+	c := newFakeClient(t, fake)
+
+	// Murmur resolves the references one by one instead.
+	refs := []string{"secret-sauce", "does-not-exist"}
+	_, errs := c.ResolveBatch(context.Background(), refs)
+
+	for i, err := range errs {
+		if !errors.Is(err, providers.ErrNotBatched) {
+			t.Errorf("ResolveBatch() error for %q = %v, want ErrNotBatched", refs[i], err)
+		}
+	}
+
+	wantCalls := []string{
+		"secretsmanager.BatchGetSecretValue",
+	}
+	if diff := cmp.Diff(wantCalls, fake.calls); diff != "" {
+		t.Errorf("API calls mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/busser/murmur/pkg/murmur/providers"
 	"github.com/google/uuid"
 )
 
@@ -16,14 +20,61 @@ type client struct {
 	awsClient *secretsmanager.Client
 }
 
+// Options configure a client. The zero value uses the environment's default
+// AWS configuration.
+type Options struct {
+	// Region overrides the AWS region, like "eu-west-3".
+	Region string
+	// Endpoint overrides the URL of the Secrets Manager API, for example to use a
+	// VPC endpoint or a local emulator.
+	Endpoint string
+	// Profile selects a profile from the shared AWS config and credentials
+	// files.
+	Profile string
+	// InsecureSkipVerify disables verification of the API's TLS certificate,
+	// for local emulators that serve self-signed certificates. It has no effect
+	// if HTTPClient is set.
+	InsecureSkipVerify bool
+	// HTTPClient sends requests to the API.
+	HTTPClient *http.Client
+}
+
 // New returns a client that fetches secrets from AWS Secrets Manager.
-func New() (*client, error) {
-	cfg, err := config.LoadDefaultConfig(context.TODO())
+func New(ctx context.Context) (*client, error) {
+	return NewWithOptions(ctx, Options{})
+}
+
+// NewWithOptions works like New, but configures the client with the given
+// options.
+func NewWithOptions(ctx context.Context, opts Options) (*client, error) {
+	loadOpts := []func(*config.LoadOptions) error{
+		// Murmur retries transient errors itself. Retrying in the SDK as well
+		// would multiply the number of attempts.
+		config.WithRetryMaxAttempts(1),
+	}
+	if opts.Region != "" {
+		loadOpts = append(loadOpts, config.WithRegion(opts.Region))
+	}
+	if opts.Profile != "" {
+		loadOpts = append(loadOpts, config.WithSharedConfigProfile(opts.Profile))
+	}
+	switch {
+	case opts.HTTPClient != nil:
+		loadOpts = append(loadOpts, config.WithHTTPClient(opts.HTTPClient))
+	case opts.InsecureSkipVerify:
+		loadOpts = append(loadOpts, config.WithHTTPClient(providers.InsecureAWSHTTPClient()))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
-	c := secretsmanager.NewFromConfig(cfg)
+	c := secretsmanager.NewFromConfig(cfg, func(o *secretsmanager.Options) {
+		if opts.Endpoint != "" {
+			o.BaseEndpoint = aws.String(opts.Endpoint)
+		}
+	})
 
 	return &client{
 		awsClient: c,
@@ -48,7 +99,15 @@ func (c *client) Resolve(ctx context.Context, ref string) (string, error) {
 
 	resp, err := c.awsClient.GetSecretValue(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("failed to get secret %q version ID: %q version stage: %q): %w", secretID, versionID, versionStage, err)
+		err = fmt.Errorf("failed to get secret %q version ID: %q version stage: %q): %w", secretID, versionID, versionStage, err)
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return "", providers.NotFound(err)
+		}
+		if isTransient(err) {
+			return "", providers.Transient(err)
+		}
+		return "", err
 	}
 
 	if resp.SecretString != nil {
@@ -57,12 +116,153 @@ func (c *client) Resolve(ctx context.Context, ref string) (string, error) {
 	return string(resp.SecretBinary), nil
 }
 
+// maxBatchSize is the most secrets BatchGetSecretValue returns in one call.
+const maxBatchSize = 20
+
+// ResolveBatch fetches the current version of secrets with BatchGetSecretValue,
+// up to 20 secrets per call. For references to other versions, or to secrets
+// BatchGetSecretValue does not return, it returns providers.ErrNotBatched, so
+// that murmur resolves them with GetSecretValue, concurrently.
+func (c *client) ResolveBatch(ctx context.Context, refs []string) ([]string, []error) {
+	values := make([]string, len(refs))
+	errs := make([]error, len(refs))
+
+	// BatchGetSecretValue only returns the AWSCURRENT version of secrets, so
+	// references to other versions must be resolved one by one.
+	var (
+		batch     []int // indices in refs
+		unbatched []int
+	)
+	for i, ref := range refs {
+		secretID, versionID, versionStage, err := parseRef(ref)
+		switch {
+		case err != nil:
+			errs[i] = fmt.Errorf("invalid reference: %w", err)
+		case secretID != "" && versionID == "" && versionStage == "AWSCURRENT":
+			batch = append(batch, i)
+		default:
+			unbatched = append(unbatched, i)
+		}
+	}
+
+	for start := 0; start < len(batch); start += maxBatchSize {
+		chunk := batch[start:min(start+maxBatchSize, len(batch))]
+		unbatched = append(unbatched, c.resolveChunk(ctx, refs, chunk, values, errs)...)
+	}
+
+	for _, i := range unbatched {
+		errs[i] = providers.ErrNotBatched
+	}
+
+	return values, errs
+}
+
+// resolveChunk fetches the secrets with the given indices in refs with a single
+// BatchGetSecretValue call, and stores results in values and errs. It returns
+// the indices of the secrets it could not fetch this way.
+func (c *client) resolveChunk(ctx context.Context, refs []string, chunk []int, values []string, errs []error) (leftovers []int) {
+	// References like "my-secret" and "my-secret#AWSCURRENT" point to the same
+	// secret, which we only ask for once.
+	var secretIDs []string
+	seen := make(map[string]bool)
+	for _, i := range chunk {
+		secretID, _, _, _ := parseRef(refs[i])
+		if !seen[secretID] {
+			secretIDs = append(secretIDs, secretID)
+			seen[secretID] = true
+		}
+	}
+
+	resp, err := c.awsClient.BatchGetSecretValue(ctx, &secretsmanager.BatchGetSecretValueInput{
+		SecretIdList: secretIDs,
+	})
+	if err != nil {
+		if isTransient(err) {
+			err = providers.Transient(fmt.Errorf("failed to get secrets %q: %w", secretIDs, err))
+			for _, i := range chunk {
+				errs[i] = err
+			}
+			return nil
+		}
+		// The caller may lack the secretsmanager:BatchGetSecretValue
+		// permission, or the endpoint may not support it. Fetching secrets one
+		// by one may still work.
+		return chunk
+	}
+
+	// Secrets come back in any order, identified by name and ARN. Errors are
+	// identified by the ID we sent.
+	done := make(map[int]bool)
+	for _, entry := range resp.SecretValues {
+		for _, i := range chunk {
+			if done[i] {
+				continue
+			}
+			secretID, _, _, _ := parseRef(refs[i])
+			if secretID != aws.ToString(entry.Name) && secretID != aws.ToString(entry.ARN) {
+				continue
+			}
+			if entry.SecretString != nil {
+				values[i] = *entry.SecretString
+			} else {
+				values[i] = string(entry.SecretBinary)
+			}
+			done[i] = true
+		}
+	}
+	for _, apiErr := range resp.Errors {
+		for _, i := range chunk {
+			if done[i] {
+				continue
+			}
+			secretID, _, _, _ := parseRef(refs[i])
+			if secretID != aws.ToString(apiErr.SecretId) {
+				continue
+			}
+			errs[i] = batchError(secretID, apiErr)
+			done[i] = true
+		}
+	}
+
+	// Partial ARNs, for example, do not match the name or ARN of the secret
+	// they point to.
+	for _, i := range chunk {
+		if !done[i] {
+			leftovers = append(leftovers, i)
+		}
+	}
+
+	return leftovers
+}
+
+// batchError returns the error BatchGetSecretValue reported for a secret,
+// classified like GetSecretValue errors.
+func batchError(secretID string, apiErr types.APIErrorType) error {
+	code := aws.ToString(apiErr.ErrorCode)
+	err := fmt.Errorf("failed to get secret %q: %s: %s", secretID, code, aws.ToString(apiErr.Message))
+
+	switch code {
+	case "ResourceNotFoundException":
+		return providers.NotFound(err)
+	case "InternalServiceError", "ThrottlingException":
+		return providers.Transient(err)
+	default:
+		return err
+	}
+}
+
 func (c *client) Close() error {
 	// The client does not need to close its underlying AWS client.
 	// ?(busser): are we sure about this? do any connections need to be closed?
 	return nil
 }
 
+// isTransient reports whether err is a throttling, server, or connection error,
+// which the AWS SDK would retry if its retries were enabled.
+func isTransient(err error) bool {
+	return retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary
+}
+
 func parseRef(ref string) (secretID, versionID, versionStage string, err error) {
 	refParts := strings.SplitN(ref, "#", 2)
 	if len(refParts) < 1 {
@@ -9,7 +9,7 @@ import (
 )
 
 func Example() {
-	c, err := awssm.New()
+	c, err := awssm.New(context.Background())
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -15,7 +15,7 @@ func TestClient(t *testing.T) {
 	// The secrets this test reads were created with Terraform. The code is in
 	// the terraform/layers/aws-secrets-manager directory of this repository.
 
-	client, err := awssm.New()
+	client, err := awssm.New(context.Background())
 	if err != nil {
 		t.Fatalf("New() returned an error: %v", err)
 	}
@@ -127,6 +127,31 @@ func TestClient(t *testing.T) {
 
 	})
 
+	// The same references, all at once.
+	t.Run("batch", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		refs := make([]string, len(tt))
+		for i, tc := range tt {
+			refs[i] = tc.ref
+		}
+
+		actualVals, errs := client.ResolveBatch(ctx, refs)
+
+		for i, tc := range tt {
+			if errs[i] != nil && !tc.wantErr {
+				t.Errorf("ResolveBatch() returned an error for %q: %v", tc.ref, errs[i])
+			}
+			if errs[i] == nil && tc.wantErr {
+				t.Errorf("ResolveBatch() did not return an error for %q", tc.ref)
+			}
+			if actualVals[i] != tc.wantVal {
+				t.Errorf("ResolveBatch() == %#v for %q, want %#v", actualVals[i], tc.ref, tc.wantVal)
+			}
+		}
+	})
+
 	if err := client.Close(); err != nil {
 		t.Fatalf("Close() returned an error: %v", err)
 	}
@@ -0,0 +1,159 @@
+package awssm
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/busser/murmur/pkg/murmur/providers"
+)
+
+// TestClientEmulated runs the same tests as TestClient, against a fake
+// Secrets Manager API served over TLS with a self-signed certificate instead of
+// AWS, so that it needs neither network access nor credentials.
+func TestClientEmulated(t *testing.T) {
+	// The fake mirrors the secrets that Terraform creates for TestClient.
+	fake := &fakeSecretsManager{
+		secrets: map[string]string{
+			"secret-sauce": "szechuan",
+		},
+		versions: map[string][]fakeVersion{
+			"secret-sauce": {
+				{id: "9AF93B18-59D6-4C19-92AC-3F69A115D404", stages: []string{"AWSCURRENT", "v2"}, value: "szechuan"},
+				{id: "97DD35A4-DD9B-4E4B-B371-9F2CA4673A41", stages: []string{"AWSPREVIOUS", "v1"}, value: "ketchup"},
+			},
+		},
+	}
+	srv := httptest.NewTLSServer(fake)
+	t.Cleanup(srv.Close)
+
+	// The API needs requests to be signed, with any credentials.
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_PROFILE", "")
+
+	client, err := NewWithOptions(context.Background(), Options{
+		Region:             "eu-west-3",
+		Endpoint:           srv.URL,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		t.Fatalf("NewWithOptions() returned an error: %v", err)
+	}
+
+	tt := []struct {
+		ref     string
+		wantVal string
+		wantErr bool
+	}{
+		// References by name.
+		{
+			ref:     "secret-sauce",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "secret-sauce#AWSCURRENT",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "secret-sauce#9AF93B18-59D6-4C19-92AC-3F69A115D404",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "secret-sauce#v2",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "secret-sauce#97DD35A4-DD9B-4E4B-B371-9F2CA4673A41",
+			wantVal: "ketchup",
+		},
+		{
+			ref:     "secret-sauce#v1",
+			wantVal: "ketchup",
+		},
+		{
+			ref:     "does-not-exist",
+			wantErr: true,
+		},
+
+		// References by ARN.
+		{
+			ref:     arn("secret-sauce"),
+			wantVal: "szechuan",
+		},
+		{
+			ref:     arn("secret-sauce") + "#AWSCURRENT",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     arn("secret-sauce") + "#9AF93B18-59D6-4C19-92AC-3F69A115D404",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     arn("secret-sauce") + "#v1",
+			wantVal: "ketchup",
+		},
+		{
+			ref:     arn("does-not-exist"),
+			wantErr: true,
+		},
+	}
+
+	t.Run("group", func(t *testing.T) {
+		for _, tc := range tt {
+			t.Run(tc.ref, func(t *testing.T) {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+
+				actualVal, err := client.Resolve(ctx, tc.ref)
+				if err != nil && !tc.wantErr {
+					t.Errorf("Resolve() returned an error: %v", err)
+				}
+				if err == nil && tc.wantErr {
+					t.Error("Resolve() did not return an error")
+				}
+				if actualVal != tc.wantVal {
+					t.Errorf("Resolve() == %#v, want %#v", actualVal, tc.wantVal)
+				}
+			})
+		}
+	})
+
+	// The same references, all at once.
+	t.Run("batch", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		refs := make([]string, len(tt))
+		for i, tc := range tt {
+			refs[i] = tc.ref
+		}
+
+		actualVals, errs := client.ResolveBatch(ctx, refs)
+
+		// Murmur resolves references left out of batches one by one.
+		for i, ref := range refs {
+			if errors.Is(errs[i], providers.ErrNotBatched) {
+				actualVals[i], errs[i] = client.Resolve(ctx, ref)
+			}
+		}
+
+		for i, tc := range tt {
+			if errs[i] != nil && !tc.wantErr {
+				t.Errorf("ResolveBatch() returned an error for %q: %v", tc.ref, errs[i])
+			}
+			if errs[i] == nil && tc.wantErr {
+				t.Errorf("ResolveBatch() did not return an error for %q", tc.ref)
+			}
+			if actualVals[i] != tc.wantVal {
+				t.Errorf("ResolveBatch() == %#v for %q, want %#v", actualVals[i], tc.ref, tc.wantVal)
+			}
+		}
+	})
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() returned an error: %v", err)
+	}
+}
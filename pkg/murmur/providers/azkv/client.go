@@ -4,31 +4,85 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/keyvault/azsecrets"
+	"github.com/busser/murmur/pkg/murmur/providers"
 )
 
 type client struct {
-	credential azcore.TokenCredential
+	credential    azcore.TokenCredential
+	clientOptions *azsecrets.ClientOptions
+	// If set, the URL of all vaults.
+	endpoint string
 
 	mu           sync.RWMutex // Protects keyvaultClients
 	vaultClients map[string]*azsecrets.Client
 }
 
+// Options configure a client. The zero value uses the environment's default
+// Azure credentials.
+type Options struct {
+	// TenantID overrides the Microsoft Entra tenant to authenticate with.
+	TenantID string
+	// Credential authenticates requests instead of the environment's default
+	// Azure credentials.
+	Credential azcore.TokenCredential
+	// Endpoint overrides the URL of every vault, like "https://localhost:8443"
+	// for a local emulator. The vault in each reference is then ignored.
+	Endpoint string
+	// InsecureSkipVerify disables verification of the vaults' TLS
+	// certificates, for emulators that serve self-signed certificates. It has no
+	// effect if HTTPClient is set.
+	InsecureSkipVerify bool
+	// HTTPClient sends requests to Azure, both to authenticate and to fetch
+	// secrets.
+	HTTPClient *http.Client
+}
+
 // New returns a client that fetches secrets from Azure Key Vault.
 func New() (*client, error) {
-	cred, err := azidentity.NewDefaultAzureCredential(nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to obtain a credential: %w", err)
+	return NewWithOptions(Options{})
+}
+
+// NewWithOptions works like New, but configures the client with the given
+// options.
+func NewWithOptions(opts Options) (*client, error) {
+	var clientOpts policy.ClientOptions
+	if opts.HTTPClient != nil {
+		clientOpts.Transport = opts.HTTPClient
 	}
 
+	cred := opts.Credential
+	if cred == nil {
+		var err error
+		cred, err = azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{
+			ClientOptions: clientOpts,
+			TenantID:      opts.TenantID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to obtain a credential: %w", err)
+		}
+	}
+
+	// Only requests to vaults skip verification, not authentication requests.
+	vaultOpts := &azsecrets.ClientOptions{ClientOptions: clientOpts}
+	if opts.HTTPClient == nil && opts.InsecureSkipVerify {
+		vaultOpts.Transport = providers.InsecureHTTPClient()
+	}
+	// Emulators cannot be in the domain of the resource they claim to be.
+	vaultOpts.DisableChallengeResourceVerification = opts.Endpoint != ""
+
 	return &client{
-		credential:   cred,
-		vaultClients: make(map[string]*azsecrets.Client),
+		credential:    cred,
+		clientOptions: vaultOpts,
+		endpoint:      opts.Endpoint,
+		vaultClients:  make(map[string]*azsecrets.Client),
 	}, nil
 }
 
@@ -48,7 +102,15 @@ func (c *client) Resolve(ctx context.Context, ref string) (string, error) {
 	// An empty string version gets the latest version of the secret.
 	resp, err := c.vaultClients[vault].GetSecret(ctx, name, version, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to get secret %q version %q: %w", name, version, err)
+		err = fmt.Errorf("failed to get secret %q version %q: %w", name, version, err)
+		var respErr *azcore.ResponseError
+		if errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound {
+			return "", providers.NotFound(err)
+		}
+		if errors.As(err, &respErr) && providers.TransientStatus(respErr.StatusCode) {
+			return "", providers.Transient(err)
+		}
+		return "", err
 	}
 
 	return *resp.Value, nil
@@ -69,7 +131,10 @@ func (c *client) createClientIfMissing(vault string) error {
 	}
 
 	vaultURL := fmt.Sprintf("https://%s/", vault)
-	azClient, err := azsecrets.NewClient(vaultURL, c.credential, nil)
+	if c.endpoint != "" {
+		vaultURL = c.endpoint
+	}
+	azClient, err := azsecrets.NewClient(vaultURL, c.credential, c.clientOptions)
 	if err != nil {
 		return fmt.Errorf("client init: %w", err)
 	}
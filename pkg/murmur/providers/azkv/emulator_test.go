@@ -0,0 +1,176 @@
+package azkv_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/busser/murmur/pkg/murmur/providers/azkv"
+)
+
+const fakeToken = "fake-token"
+
+// fakeCredential hands out a token that fakeKeyVault accepts.
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(context.Context, policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: fakeToken, ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// fakeKeyVault serves the parts of the Key Vault API the client uses, like
+// Key Vault emulators do.
+type fakeKeyVault struct {
+	// Versions of secrets, by name and version ID.
+	secrets map[string]map[string]string
+	// Latest version ID of each secret, by name.
+	latest map[string]string
+}
+
+func (f *fakeKeyVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Clients first send an unauthenticated request, to find out where to get a
+	// token from.
+	if r.Header.Get("Authorization") != "Bearer "+fakeToken {
+		w.Header().Set("WWW-Authenticate", `Bearer authorization="https://login.microsoftonline.com/00000000-0000-0000-0000-000000000000", resource="https://vault.azure.net"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	name, version, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/secrets/"), "/")
+	if version == "" {
+		version = f.latest[name]
+	}
+
+	value, ok := f.secrets[name][version]
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]any{
+			"error": map[string]string{
+				"code":    "SecretNotFound",
+				"message": "A secret with (name/id) " + name + " was not found in this key vault.",
+			},
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"id":    "https://" + r.Host + "/secrets/" + name + "/" + version,
+		"value": value,
+	})
+}
+
+// TestClientEmulated runs the same tests as TestClient, against a fake Key
+// Vault API served over TLS with a self-signed certificate instead of Azure, so
+// that it needs neither network access nor credentials.
+func TestClientEmulated(t *testing.T) {
+	// The fake mirrors the secrets that Terraform creates for TestClient. With a
+	// custom endpoint, all vaults are the same, so the fake holds the secrets of
+	// both vaults.
+	fake := &fakeKeyVault{
+		secrets: map[string]map[string]string{
+			"secret-sauce": {
+				"788ffd5cd2224f67b98e12f6fc0cd720": "szechuan",
+				"02fc2105c6b34f8385a2ee8531e4900f": "ketchup",
+				"48b0d307869b4cf9a0141a062ecdc648": "szechuan",
+				"e34b3d09f61f4ed1a1812b88834bcb3e": "ketchup",
+			},
+		},
+		latest: map[string]string{
+			"secret-sauce": "788ffd5cd2224f67b98e12f6fc0cd720",
+		},
+	}
+	srv := httptest.NewTLSServer(fake)
+	t.Cleanup(srv.Close)
+
+	client, err := azkv.NewWithOptions(azkv.Options{
+		Credential:         fakeCredential{},
+		Endpoint:           srv.URL,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		t.Fatalf("NewWithOptions() returned an error: %v", err)
+	}
+
+	tt := []struct {
+		ref     string
+		wantVal string
+		wantErr bool
+	}{
+		// Secrets in the alpha vault.
+		{
+			ref:     "murmur-alpha.vault.azure.net/secret-sauce",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "murmur-alpha.vault.azure.net/secret-sauce#788ffd5cd2224f67b98e12f6fc0cd720",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "murmur-alpha.vault.azure.net/secret-sauce#02fc2105c6b34f8385a2ee8531e4900f",
+			wantVal: "ketchup",
+		},
+		{
+			ref:     "murmur-alpha.vault.azure.net/does-not-exist",
+			wantErr: true,
+		},
+
+		// Secrets in the bravo vault.
+		{
+			ref:     "murmur-bravo.vault.azure.net/secret-sauce",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "murmur-bravo.vault.azure.net/secret-sauce#48b0d307869b4cf9a0141a062ecdc648",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "murmur-bravo.vault.azure.net/secret-sauce#e34b3d09f61f4ed1a1812b88834bcb3e",
+			wantVal: "ketchup",
+		},
+		{
+			ref:     "murmur-bravo.vault.azure.net/does-not-exist",
+			wantErr: true,
+		},
+
+		// Invalid references.
+		{
+			ref:     "invalid-ref",
+			wantErr: true,
+		},
+	}
+
+	// Test cases are grouped such that they run in parallel and we can perform
+	// cleanup once they are done.
+	t.Run("group", func(t *testing.T) {
+		for _, tc := range tt {
+			t.Run(tc.ref, func(t *testing.T) {
+				t.Parallel()
+
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+
+				actualVal, err := client.Resolve(ctx, tc.ref)
+				if err != nil && !tc.wantErr {
+					t.Errorf("Resolve() returned an error: %v", err)
+				}
+				if err == nil && tc.wantErr {
+					t.Error("Resolve() did not return an error")
+				}
+				if actualVal != tc.wantVal {
+					t.Errorf("Resolve() == %#v, want %#v", actualVal, tc.wantVal)
+				}
+			})
+		}
+	})
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() returned an error: %v", err)
+	}
+}
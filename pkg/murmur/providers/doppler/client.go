@@ -0,0 +1,218 @@
+package doppler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/busser/murmur/pkg/murmur/providers"
+)
+
+const defaultAPIHost = "https://api.doppler.com"
+
+type client struct {
+	httpClient *http.Client
+	apiHost    string
+	token      string
+
+	mu        sync.Mutex // protects downloads
+	downloads map[string]*download
+}
+
+// download holds the result of downloading all secrets of a config. The done
+// channel is closed once secrets and err are set.
+type download struct {
+	done    chan struct{}
+	secrets map[string]string
+	err     error
+}
+
+// Options configure a client.
+type Options struct {
+	// InsecureSkipVerify disables verification of the API's TLS certificate,
+	// for mock servers at DOPPLER_API_HOST that serve self-signed certificates.
+	InsecureSkipVerify bool
+}
+
+// New returns a client that fetches secrets from Doppler. The client
+// authenticates with the service token in DOPPLER_TOKEN.
+//
+// Doppler's API returns all secrets of a config at once, so the client
+// downloads each config only once and serves all references to that config from
+// that single download.
+func New() (*client, error) {
+	return NewWithOptions(Options{})
+}
+
+// NewWithOptions works like New, but configures the client with the given
+// options.
+func NewWithOptions(opts Options) (*client, error) {
+	token := os.Getenv("DOPPLER_TOKEN")
+	if token == "" {
+		return nil, errors.New("DOPPLER_TOKEN is not set")
+	}
+
+	apiHost := defaultAPIHost
+	if h := os.Getenv("DOPPLER_API_HOST"); h != "" {
+		apiHost = h
+	}
+
+	httpClient := &http.Client{}
+	if opts.InsecureSkipVerify {
+		httpClient = providers.InsecureHTTPClient()
+	}
+
+	return &client{
+		httpClient: httpClient,
+		apiHost:    strings.TrimSuffix(apiHost, "/"),
+		token:      token,
+		downloads:  make(map[string]*download),
+	}, nil
+}
+
+func (c *client) Resolve(ctx context.Context, ref string) (string, error) {
+	project, config, name, err := parseRef(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid reference: %w", err)
+	}
+
+	secrets, err := c.configSecrets(ctx, project, config)
+	if err != nil {
+		err = fmt.Errorf("failed to download secrets of config %q in project %q: %w", config, project, err)
+		var respErr *responseError
+		if errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound {
+			return "", providers.NotFound(err)
+		}
+		return "", err
+	}
+
+	value, ok := secrets[name]
+	if !ok {
+		return "", providers.NotFound(fmt.Errorf("config %q in project %q has no secret %q", config, project, name))
+	}
+
+	return value, nil
+}
+
+func (c *client) Close() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// configSecrets returns all secrets of the given config. Concurrent calls for
+// the same config share a single download. Successful downloads are reused by
+// later calls; failed ones are not.
+func (c *client) configSecrets(ctx context.Context, project, config string) (map[string]string, error) {
+	key := project + "/" + config
+
+	c.mu.Lock()
+	d, ok := c.downloads[key]
+	if !ok {
+		d = &download{done: make(chan struct{})}
+		c.downloads[key] = d
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		d.secrets, d.err = c.download(ctx, project, config)
+		if d.err != nil {
+			// Later calls, like retries, must download the config again
+			// instead of getting the same error.
+			c.mu.Lock()
+			delete(c.downloads, key)
+			c.mu.Unlock()
+		}
+		close(d.done)
+	}
+
+	select {
+	case <-d.done:
+		return d.secrets, d.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *client) download(ctx context.Context, project, config string) (map[string]string, error) {
+	query := url.Values{}
+	query.Set("project", project)
+	query.Set("config", config)
+	query.Set("format", "json")
+
+	u := c.apiHost + "/v3/configs/config/secrets/download?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := newResponseError(resp)
+		if providers.TransientStatus(resp.StatusCode) {
+			return nil, providers.Transient(err)
+		}
+		return nil, err
+	}
+
+	var secrets map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&secrets); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return secrets, nil
+}
+
+// responseError is returned when Doppler responds with an error status code.
+type responseError struct {
+	StatusCode int
+	Messages   []string
+}
+
+func newResponseError(resp *http.Response) *responseError {
+	var body struct {
+		Messages []string `json:"messages"`
+	}
+	// The body may be empty or not JSON, in which case we only report the
+	// status code.
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+
+	return &responseError{
+		StatusCode: resp.StatusCode,
+		Messages:   body.Messages,
+	}
+}
+
+func (e *responseError) Error() string {
+	if len(e.Messages) == 0 {
+		return fmt.Sprintf("doppler responded with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("doppler responded with status %d: %s", e.StatusCode, strings.Join(e.Messages, "; "))
+}
+
+func parseRef(ref string) (project, config, name string, err error) {
+	refParts := strings.Split(ref, "/")
+	if len(refParts) != 3 {
+		return "", "", "", errors.New("invalid syntax")
+	}
+
+	project, config, name = refParts[0], refParts[1], refParts[2]
+	if project == "" || config == "" || name == "" {
+		return "", "", "", errors.New("project, config, and secret name cannot be empty")
+	}
+
+	return project, config, name, nil
+}
@@ -0,0 +1,266 @@
+package doppler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/busser/murmur/pkg/murmur/providers"
+	"github.com/busser/murmur/pkg/murmur/providers/doppler"
+)
+
+func Example() {
+	c, err := doppler.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ref := "kitchen/prd/SECRET_SAUCE"
+	val, err := c.Resolve(context.Background(), ref)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("The secret sauce is", val)
+}
+
+const testToken = "dp.st.test"
+
+// fakeDoppler mimics the config download endpoint of the Doppler API, and
+// counts how many times each config was downloaded.
+type fakeDoppler struct {
+	mu        sync.Mutex
+	downloads map[string]int
+}
+
+func (f *fakeDoppler) downloadCount(project, config string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.downloads[project+"/"+config]
+}
+
+func newFakeDoppler(t *testing.T) (*httptest.Server, *fakeDoppler) {
+	t.Helper()
+
+	configs := map[string]map[string]string{
+		"kitchen/prd": {
+			"SECRET_SAUCE": "szechuan",
+			"OLD_SAUCE":    "ketchup",
+		},
+		"kitchen/dev": {
+			"SECRET_SAUCE": "mayonnaise",
+		},
+	}
+
+	fake := &fakeDoppler{downloads: make(map[string]int)}
+
+	reply := func(w http.ResponseWriter, status int, body any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v3/configs/config/secrets/download", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testToken {
+			reply(w, http.StatusUnauthorized, map[string]any{"messages": []string{"Invalid Auth token"}, "success": false})
+			return
+		}
+
+		key := r.URL.Query().Get("project") + "/" + r.URL.Query().Get("config")
+
+		fake.mu.Lock()
+		fake.downloads[key]++
+		fake.mu.Unlock()
+
+		secrets, ok := configs[key]
+		if !ok {
+			reply(w, http.StatusNotFound, map[string]any{"messages": []string{"Could not find requested config"}, "success": false})
+			return
+		}
+		reply(w, http.StatusOK, secrets)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv, fake
+}
+
+func TestClient(t *testing.T) {
+	srv, fake := newFakeDoppler(t)
+
+	t.Setenv("DOPPLER_API_HOST", srv.URL)
+	t.Setenv("DOPPLER_TOKEN", testToken)
+
+	client, err := doppler.New()
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+
+	tt := []struct {
+		ref          string
+		wantVal      string
+		wantErr      bool
+		wantNotFound bool
+	}{
+		{
+			ref:     "kitchen/prd/SECRET_SAUCE",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "kitchen/prd/OLD_SAUCE",
+			wantVal: "ketchup",
+		},
+		{
+			ref:     "kitchen/dev/SECRET_SAUCE",
+			wantVal: "mayonnaise",
+		},
+		{
+			ref:          "kitchen/dev/OLD_SAUCE",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			ref:          "kitchen/stg/SECRET_SAUCE",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			ref:     "kitchen/SECRET_SAUCE",
+			wantErr: true,
+		},
+		{
+			ref:     "kitchen//SECRET_SAUCE",
+			wantErr: true,
+		},
+	}
+
+	// Test cases are grouped such that they run in parallel and we can perform
+	// checks once they are done.
+	t.Run("group", func(t *testing.T) {
+		for _, tc := range tt {
+			t.Run(tc.ref, func(t *testing.T) {
+				t.Parallel()
+
+				actualVal, err := client.Resolve(context.Background(), tc.ref)
+				if err != nil && !tc.wantErr {
+					t.Errorf("Resolve() returned an error: %v", err)
+				}
+				if err == nil && tc.wantErr {
+					t.Error("Resolve() did not return an error")
+				}
+				if errors.Is(err, providers.ErrNotFound) != tc.wantNotFound {
+					t.Errorf("errors.Is(err, ErrNotFound) == %t, want %t", !tc.wantNotFound, tc.wantNotFound)
+				}
+				if actualVal != tc.wantVal {
+					t.Errorf("Resolve() == %#v, want %#v", actualVal, tc.wantVal)
+				}
+			})
+		}
+	})
+
+	for _, config := range []string{"prd", "dev", "stg"} {
+		if n := fake.downloadCount("kitchen", config); n != 1 {
+			t.Errorf("config %q downloaded %d times, want 1", config, n)
+		}
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() returned an error: %v", err)
+	}
+}
+
+func TestClientInsecureSkipVerify(t *testing.T) {
+	fakeSrv, _ := newFakeDoppler(t)
+	srv := httptest.NewTLSServer(fakeSrv.Config.Handler)
+	t.Cleanup(srv.Close)
+
+	t.Setenv("DOPPLER_API_HOST", srv.URL)
+	t.Setenv("DOPPLER_TOKEN", testToken)
+
+	// The server's certificate is self-signed.
+	client, err := doppler.New()
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.Resolve(context.Background(), "kitchen/prd/SECRET_SAUCE"); err == nil {
+		t.Error("Resolve() did not return an error")
+	}
+
+	client, err = doppler.NewWithOptions(doppler.Options{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("NewWithOptions() returned an error: %v", err)
+	}
+	defer client.Close()
+
+	val, err := client.Resolve(context.Background(), "kitchen/prd/SECRET_SAUCE")
+	if err != nil {
+		t.Fatalf("Resolve() returned an error: %v", err)
+	}
+	if val != "szechuan" {
+		t.Errorf("Resolve() == %#v, want %#v", val, "szechuan")
+	}
+}
+
+func TestClientDownloadsAgainAfterFailure(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		// Doppler is briefly unavailable.
+		if n == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"SECRET_SAUCE": "szechuan"})
+	}))
+	t.Cleanup(srv.Close)
+
+	t.Setenv("DOPPLER_API_HOST", srv.URL)
+	t.Setenv("DOPPLER_TOKEN", testToken)
+
+	client, err := doppler.New()
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.Resolve(context.Background(), "kitchen/prd/SECRET_SAUCE")
+	if !errors.Is(err, providers.ErrTransient) {
+		t.Fatalf("Resolve() returned %v, want a transient error", err)
+	}
+
+	// A retry reaches Doppler again, instead of getting the same error.
+	val, err := client.Resolve(context.Background(), "kitchen/prd/SECRET_SAUCE")
+	if err != nil {
+		t.Fatalf("Resolve() returned an error: %v", err)
+	}
+	if val != "szechuan" {
+		t.Errorf("Resolve() == %#v, want %#v", val, "szechuan")
+	}
+
+	// The successful download is reused.
+	if _, err := client.Resolve(context.Background(), "kitchen/prd/SECRET_SAUCE"); err != nil {
+		t.Fatalf("Resolve() returned an error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Doppler received %d requests, want 2", requests)
+	}
+}
@@ -0,0 +1,65 @@
+package providers
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrNotFound means that the secret a reference points to does not exist.
+// Providers wrap their store-specific not-found errors with NotFound, so that
+// murmur can tell missing secrets apart from other failures, like denied
+// permissions or network errors.
+var ErrNotFound = errors.New("secret not found")
+
+// ErrTransient means that a provider failed for a reason that may go away if
+// murmur tries again, like throttling or a server error. Providers wrap these
+// errors with Transient, so that murmur knows which failures to retry.
+var ErrTransient = errors.New("transient failure")
+
+// ErrNotBatched means that a batch provider did not fetch a secret with the
+// rest of its batch, for example because the secret store cannot return that
+// version of the secret in a batch. Murmur then resolves the reference with
+// Resolve, like it does for providers without batches.
+var ErrNotBatched = errors.New("not resolved in batch")
+
+// NotFound marks err as a not-found error, such that errors.Is(NotFound(err),
+// ErrNotFound) is true. The returned error has the same message as err, and
+// still wraps it.
+func NotFound(err error) error {
+	return &markedError{mark: ErrNotFound, err: err}
+}
+
+// Transient marks err as a transient error, such that
+// errors.Is(Transient(err), ErrTransient) is true. The returned error has the
+// same message as err, and still wraps it.
+func Transient(err error) error {
+	return &markedError{mark: ErrTransient, err: err}
+}
+
+// TransientStatus reports whether an HTTP response with the given status code
+// is worth retrying: timeouts, throttling, and server errors are.
+func TransientStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	case http.StatusNotImplemented, http.StatusHTTPVersionNotSupported:
+		return false
+	default:
+		return code >= 500 && code <= 599
+	}
+}
+
+// markedError wraps an error with one of the sentinel errors above, without
+// changing its message.
+type markedError struct {
+	mark error
+	err  error
+}
+
+func (e *markedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *markedError) Unwrap() []error {
+	return []error{e.mark, e.err}
+}
@@ -0,0 +1,71 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestNotFound(t *testing.T) {
+	original := errors.New("no such secret")
+
+	err := fmt.Errorf("failed to get secret: %w", NotFound(original))
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Error("errors.Is(err, ErrNotFound) is false, want true")
+	}
+	if !errors.Is(err, original) {
+		t.Error("errors.Is(err, original) is false, want true")
+	}
+	if want := "failed to get secret: no such secret"; err.Error() != want {
+		t.Errorf("err.Error() = %q, want %q", err.Error(), want)
+	}
+
+	if errors.Is(original, ErrNotFound) {
+		t.Error("errors.Is(original, ErrNotFound) is true, want false")
+	}
+}
+
+func TestTransient(t *testing.T) {
+	original := errors.New("too many requests")
+
+	err := fmt.Errorf("failed to get secret: %w", Transient(original))
+
+	if !errors.Is(err, ErrTransient) {
+		t.Error("errors.Is(err, ErrTransient) is false, want true")
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Error("errors.Is(err, ErrNotFound) is true, want false")
+	}
+	if !errors.Is(err, original) {
+		t.Error("errors.Is(err, original) is false, want true")
+	}
+	if want := "failed to get secret: too many requests"; err.Error() != want {
+		t.Errorf("err.Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestTransientStatus(t *testing.T) {
+	tt := []struct {
+		code int
+		want bool
+	}{
+		{200, false},
+		{400, false},
+		{403, false},
+		{404, false},
+		{408, true},
+		{429, true},
+		{500, true},
+		{501, false},
+		{502, true},
+		{503, true},
+		{504, true},
+	}
+
+	for _, tc := range tt {
+		if actual := TransientStatus(tc.code); actual != tc.want {
+			t.Errorf("TransientStatus(%d) = %t, want %t", tc.code, actual, tc.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package flakymock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/busser/murmur/pkg/murmur/providers"
+)
+
+type client struct {
+	mu           sync.RWMutex
+	failures     int
+	attempts     map[string]int
+	resolvedRefs []string
+	closed       bool
+}
+
+// New returns a client useful for testing retries. It fails with a transient
+// error the first `failures` times it resolves each reference, and provides
+// deterministic values after that.
+func New(failures int) *client {
+	return &client{
+		failures: failures,
+		attempts: make(map[string]int),
+	}
+}
+
+func (c *client) Resolve(ctx context.Context, ref string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.attempts[ref]++
+	if c.attempts[ref] <= c.failures {
+		return "", providers.Transient(ErrorFor(ref))
+	}
+
+	c.resolvedRefs = append(c.resolvedRefs, ref)
+
+	return ValueFor(ref), nil
+}
+
+func (c *client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return errors.New("already closed")
+	}
+
+	c.closed = true
+
+	return nil
+}
+
+func (c *client) Closed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.closed
+}
+
+// ResolvedRefs returns the references the client successfully resolved.
+func (c *client) ResolvedRefs() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.resolvedRefs
+}
+
+// Attempts returns how many times the client was asked to resolve ref.
+func (c *client) Attempts(ref string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.attempts[ref]
+}
+
+func ValueFor(ref string) string {
+	return fmt.Sprintf("flaky mock value for ref %q", ref)
+}
+
+func ErrorFor(ref string) error {
+	return fmt.Errorf("ref %q is temporarily unavailable", ref)
+}
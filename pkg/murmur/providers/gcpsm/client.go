@@ -2,21 +2,80 @@ package gcpsm
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"strings"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/busser/murmur/pkg/murmur/providers"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 type client struct {
 	gcpClient *secretmanager.Client
 }
 
+// Options configure a client. The zero value uses the environment's
+// Application Default Credentials.
+type Options struct {
+	// Endpoint overrides the address of the Secret Manager API, like
+	// "secretmanager.europe-west1.rep.googleapis.com:443" for a regional
+	// endpoint.
+	Endpoint string
+	// CredentialsFile is the path of a service account key or other
+	// credentials file to use instead of Application Default Credentials.
+	CredentialsFile string
+	// InsecureSkipVerify disables verification of the API's TLS certificate,
+	// for emulators that serve self-signed certificates.
+	InsecureSkipVerify bool
+	// Insecure connects to the API without TLS or authentication, as local
+	// emulators of Secret Manager expect. Endpoint must be set too.
+	Insecure bool
+	// ClientOptions are passed as-is to the Secret Manager client, after any
+	// options derived from the fields above.
+	ClientOptions []option.ClientOption
+}
+
 // New returns a client that fetches secrets from Google Secret Manager.
-func New() (*client, error) {
-	c, err := secretmanager.NewClient(context.TODO())
+func New(ctx context.Context) (*client, error) {
+	return NewWithOptions(ctx, Options{})
+}
+
+// NewWithOptions works like New, but configures the client with the given
+// options.
+func NewWithOptions(ctx context.Context, opts Options) (*client, error) {
+	var clientOpts []option.ClientOption
+	if opts.Endpoint != "" {
+		clientOpts = append(clientOpts, option.WithEndpoint(opts.Endpoint))
+	}
+	if opts.CredentialsFile != "" {
+		clientOpts = append(clientOpts, option.WithCredentialsFile(opts.CredentialsFile))
+	}
+	switch {
+	case opts.Insecure:
+		if opts.Endpoint == "" {
+			return nil, errors.New("connecting without TLS requires an endpoint")
+		}
+		clientOpts = append(clientOpts,
+			option.WithoutAuthentication(),
+			option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		)
+	case opts.InsecureSkipVerify:
+		tlsConfig := &tls.Config{InsecureSkipVerify: true}
+		clientOpts = append(clientOpts,
+			option.WithGRPCDialOption(grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))),
+		)
+	}
+	clientOpts = append(clientOpts, opts.ClientOptions...)
+
+	c, err := secretmanager.NewClient(ctx, clientOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to setup client: %w", err)
 	}
@@ -37,7 +96,15 @@ func (c *client) Resolve(ctx context.Context, ref string) (string, error) {
 	}
 	resp, err := c.gcpClient.AccessSecretVersion(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("failed to access secret %q version %q: %v", name, version, err)
+		accessErr := fmt.Errorf("failed to access secret %q version %q: %w", name, version, err)
+		switch status.Code(err) {
+		case codes.NotFound:
+			return "", providers.NotFound(accessErr)
+		case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.Internal, codes.DeadlineExceeded:
+			return "", providers.Transient(accessErr)
+		default:
+			return "", accessErr
+		}
 	}
 
 	return string(resp.Payload.Data), nil
@@ -9,7 +9,7 @@ import (
 )
 
 func Example() {
-	c, err := gcpsm.New()
+	c, err := gcpsm.New(context.Background())
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -15,7 +15,7 @@ func TestClient(t *testing.T) {
 	// The secrets this test reads were created with Terraform. The code is in
 	// the terraform/layers/gcp-secret-manager directory of this repository.
 
-	client, err := gcpsm.New()
+	client, err := gcpsm.New(context.Background())
 	if err != nil {
 		t.Fatalf("New() returned an error: %v", err)
 	}
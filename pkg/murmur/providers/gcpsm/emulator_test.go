@@ -0,0 +1,182 @@
+package gcpsm_test
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/busser/murmur/pkg/murmur/providers/gcpsm"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// fakeSecretManager serves the parts of the Secret Manager API the client
+// uses, like the Secret Manager emulator does.
+type fakeSecretManager struct {
+	secretmanagerpb.UnimplementedSecretManagerServiceServer
+
+	// Versions of secrets, by full name like "projects/p/secrets/s", oldest
+	// first.
+	secrets map[string][]string
+}
+
+func (f *fakeSecretManager) AccessSecretVersion(_ context.Context, req *secretmanagerpb.AccessSecretVersionRequest) (*secretmanagerpb.AccessSecretVersionResponse, error) {
+	secret, version, ok := strings.Cut(req.GetName(), "/versions/")
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid name %q", req.GetName())
+	}
+
+	versions, ok := f.secrets[secret]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "secret %q not found", secret)
+	}
+
+	n := len(versions)
+	if version != "latest" {
+		var err error
+		n, err = strconv.Atoi(version)
+		if err != nil || n < 1 || n > len(versions) {
+			return nil, status.Errorf(codes.NotFound, "version %q of secret %q not found", version, secret)
+		}
+	}
+
+	return &secretmanagerpb.AccessSecretVersionResponse{
+		Name:    secret + "/versions/" + strconv.Itoa(n),
+		Payload: &secretmanagerpb.SecretPayload{Data: []byte(versions[n-1])},
+	}, nil
+}
+
+// serveFake serves a fake Secret Manager API on a random local port, and
+// returns its address.
+func serveFake(t *testing.T, opts ...grpc.ServerOption) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	srv := grpc.NewServer(opts...)
+	// The fake mirrors the secrets that Terraform creates for TestClient.
+	secretmanagerpb.RegisterSecretManagerServiceServer(srv, &fakeSecretManager{
+		secrets: map[string][]string{
+			"projects/murmur-tests/secrets/secret-sauce": {"ketchup", "szechuan"},
+		},
+	})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	return lis.Addr().String()
+}
+
+// selfSignedCertificate returns a certificate for 127.0.0.1 that no one trusts.
+func selfSignedCertificate() tls.Certificate {
+	srv := httptest.NewTLSServer(nil)
+	defer srv.Close()
+	return srv.TLS.Certificates[0]
+}
+
+// TestClientEmulated runs the same tests as TestClient, against a fake Secret
+// Manager API instead of Google Cloud, so that it needs neither network access
+// nor credentials.
+func TestClientEmulated(t *testing.T) {
+	cert := selfSignedCertificate()
+
+	emulators := map[string]func(t *testing.T) gcpsm.Options{
+		// Like the Secret Manager emulator.
+		"without TLS": func(t *testing.T) gcpsm.Options {
+			return gcpsm.Options{
+				Endpoint: serveFake(t),
+				Insecure: true,
+			}
+		},
+		"with self-signed certificate": func(t *testing.T) gcpsm.Options {
+			return gcpsm.Options{
+				Endpoint:           serveFake(t, grpc.Creds(credentials.NewServerTLSFromCert(&cert))),
+				InsecureSkipVerify: true,
+				ClientOptions:      []option.ClientOption{option.WithoutAuthentication()},
+			}
+		},
+	}
+
+	tt := []struct {
+		ref     string
+		wantVal string
+		wantErr bool
+		// The gRPC status code errors must carry, if any.
+		wantCode codes.Code
+	}{
+		{
+			ref:     "murmur-tests/secret-sauce",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "murmur-tests/secret-sauce#2",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "murmur-tests/secret-sauce#1",
+			wantVal: "ketchup",
+		},
+		{
+			ref:      "murmur-tests/does-not-exist",
+			wantErr:  true,
+			wantCode: codes.NotFound,
+		},
+		{
+			ref:     "invalid-ref",
+			wantErr: true,
+		},
+	}
+
+	for name, emulator := range emulators {
+		t.Run(name, func(t *testing.T) {
+			client, err := gcpsm.NewWithOptions(context.Background(), emulator(t))
+			if err != nil {
+				t.Fatalf("NewWithOptions() returned an error: %v", err)
+			}
+			t.Cleanup(func() {
+				if err := client.Close(); err != nil {
+					t.Errorf("Close() returned an error: %v", err)
+				}
+			})
+
+			for _, tc := range tt {
+				t.Run(tc.ref, func(t *testing.T) {
+					t.Parallel()
+
+					ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+					defer cancel()
+
+					actualVal, err := client.Resolve(ctx, tc.ref)
+					if err != nil && !tc.wantErr {
+						t.Errorf("Resolve() returned an error: %v", err)
+					}
+					if err == nil && tc.wantErr {
+						t.Error("Resolve() did not return an error")
+					}
+					if tc.wantCode != codes.OK && status.Code(err) != tc.wantCode {
+						t.Errorf("status.Code(err) == %v, want %v", status.Code(err), tc.wantCode)
+					}
+					if actualVal != tc.wantVal {
+						t.Errorf("Resolve() == %#v, want %#v", actualVal, tc.wantVal)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestNewWithOptionsInsecureWithoutEndpoint(t *testing.T) {
+	_, err := gcpsm.NewWithOptions(context.Background(), gcpsm.Options{Insecure: true})
+	if err == nil {
+		t.Error("NewWithOptions() did not return an error")
+	}
+}
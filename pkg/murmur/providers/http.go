@@ -0,0 +1,32 @@
+package providers
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+)
+
+// InsecureHTTPClient returns an HTTP client that does not verify the TLS
+// certificates of the servers it talks to. Providers use it to reach local
+// emulators and mock servers, which usually serve self-signed certificates.
+// Never use it to reach a real secret store.
+func InsecureHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		InsecureSkipVerify: true,
+	}
+	return &http.Client{Transport: transport}
+}
+
+// InsecureAWSHTTPClient works like InsecureHTTPClient, for the AWS SDK. Unlike
+// a plain HTTP client, the SDK can still add certificate authorities to it,
+// from AWS_CA_BUNDLE for example.
+func InsecureAWSHTTPClient() *awshttp.BuildableClient {
+	return awshttp.NewBuildableClient().WithTransportOptions(func(tr *http.Transport) {
+		if tr.TLSClientConfig == nil {
+			tr.TLSClientConfig = &tls.Config{}
+		}
+		tr.TLSClientConfig.InsecureSkipVerify = true
+	})
+}
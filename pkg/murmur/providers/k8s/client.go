@@ -0,0 +1,162 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/busser/murmur/pkg/murmur/providers"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// kind is the kind of Kubernetes object a client reads values from.
+type kind string
+
+const (
+	kindSecret    kind = "secret"
+	kindConfigMap kind = "configmap"
+)
+
+type client struct {
+	k8sClient kubernetes.Interface
+	kind      kind
+}
+
+// New returns a client that fetches values from Kubernetes Secrets.
+func New() (*client, error) {
+	return newWithKind(kindSecret)
+}
+
+// NewConfigMaps returns a client that fetches values from Kubernetes
+// ConfigMaps.
+func NewConfigMaps() (*client, error) {
+	return newWithKind(kindConfigMap)
+}
+
+func newWithKind(k kind) (*client, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Kubernetes config: %w", err)
+	}
+
+	c, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup client: %w", err)
+	}
+
+	return &client{
+		k8sClient: c,
+		kind:      k,
+	}, nil
+}
+
+// loadConfig returns the in-cluster config when running in a pod, and the
+// config from KUBECONFIG or ~/.kube/config otherwise.
+func loadConfig() (*rest.Config, error) {
+	cfg, err := rest.InClusterConfig()
+	if err == nil {
+		return cfg, nil
+	}
+	if !errors.Is(err, rest.ErrNotInCluster) {
+		return nil, err
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	kubeconfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, nil)
+
+	return kubeconfig.ClientConfig()
+}
+
+func (c *client) Resolve(ctx context.Context, ref string) (string, error) {
+	namespace, name, key, err := parseRef(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid reference: %w", err)
+	}
+
+	switch c.kind {
+	case kindSecret:
+		return c.resolveSecret(ctx, namespace, name, key)
+	case kindConfigMap:
+		return c.resolveConfigMap(ctx, namespace, name, key)
+	default:
+		return "", fmt.Errorf("unknown kind %q", c.kind)
+	}
+}
+
+func (c *client) resolveSecret(ctx context.Context, namespace, name, key string) (string, error) {
+	secret, err := c.k8sClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		err = fmt.Errorf("failed to get secret %q in namespace %q: %w", name, namespace, err)
+		if apierrors.IsNotFound(err) {
+			return "", providers.NotFound(err)
+		}
+		if isTransient(err) {
+			return "", providers.Transient(err)
+		}
+		return "", err
+	}
+
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", providers.NotFound(fmt.Errorf("secret %q in namespace %q has no key %q", name, namespace, key))
+	}
+
+	return string(value), nil
+}
+
+func (c *client) resolveConfigMap(ctx context.Context, namespace, name, key string) (string, error) {
+	configMap, err := c.k8sClient.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		err = fmt.Errorf("failed to get configmap %q in namespace %q: %w", name, namespace, err)
+		if apierrors.IsNotFound(err) {
+			return "", providers.NotFound(err)
+		}
+		if isTransient(err) {
+			return "", providers.Transient(err)
+		}
+		return "", err
+	}
+
+	if value, ok := configMap.Data[key]; ok {
+		return value, nil
+	}
+	if value, ok := configMap.BinaryData[key]; ok {
+		return string(value), nil
+	}
+
+	return "", providers.NotFound(fmt.Errorf("configmap %q in namespace %q has no key %q", name, namespace, key))
+}
+
+// isTransient reports whether err is a throttling or server error.
+func isTransient(err error) bool {
+	return apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsInternalError(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsUnexpectedServerError(err)
+}
+
+func (c *client) Close() error {
+	// The client does not need to close its underlying Kubernetes client.
+	return nil
+}
+
+func parseRef(ref string) (namespace, name, key string, err error) {
+	refParts := strings.Split(ref, "/")
+	if len(refParts) != 3 {
+		return "", "", "", errors.New("invalid syntax")
+	}
+
+	namespace, name, key = refParts[0], refParts[1], refParts[2]
+	if namespace == "" || name == "" || key == "" {
+		return "", "", "", errors.New("namespace, name, and key cannot be empty")
+	}
+
+	return namespace, name, key, nil
+}
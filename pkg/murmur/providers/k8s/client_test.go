@@ -0,0 +1,142 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/busser/murmur/pkg/murmur/providers"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestClient(t *testing.T) {
+	clientset := fake.NewClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kitchen", Name: "sauces"},
+			Data: map[string][]byte{
+				"secret-sauce": []byte("szechuan"),
+				"old-sauce":    []byte("ketchup"),
+			},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kitchen", Name: "recipes"},
+			Data: map[string]string{
+				"secret-sauce": "szechuan",
+			},
+			BinaryData: map[string][]byte{
+				"picture": []byte("\x89PNG"),
+			},
+		},
+	)
+
+	tt := []struct {
+		kind         kind
+		ref          string
+		wantVal      string
+		wantErr      bool
+		wantNotFound bool
+	}{
+		// Secrets.
+		{
+			kind:    kindSecret,
+			ref:     "kitchen/sauces/secret-sauce",
+			wantVal: "szechuan",
+		},
+		{
+			kind:    kindSecret,
+			ref:     "kitchen/sauces/old-sauce",
+			wantVal: "ketchup",
+		},
+		{
+			kind:         kindSecret,
+			ref:          "kitchen/sauces/does-not-exist",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			kind:         kindSecret,
+			ref:          "kitchen/does-not-exist/secret-sauce",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			kind:         kindSecret,
+			ref:          "garage/sauces/secret-sauce",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			kind:         kindSecret,
+			ref:          "kitchen/recipes/secret-sauce",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+
+		// ConfigMaps.
+		{
+			kind:    kindConfigMap,
+			ref:     "kitchen/recipes/secret-sauce",
+			wantVal: "szechuan",
+		},
+		{
+			kind:    kindConfigMap,
+			ref:     "kitchen/recipes/picture",
+			wantVal: "\x89PNG",
+		},
+		{
+			kind:         kindConfigMap,
+			ref:          "kitchen/recipes/does-not-exist",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			kind:         kindConfigMap,
+			ref:          "kitchen/sauces/secret-sauce",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+
+		// Invalid references.
+		{
+			kind:    kindSecret,
+			ref:     "sauces/secret-sauce",
+			wantErr: true,
+		},
+		{
+			kind:    kindSecret,
+			ref:     "kitchen//secret-sauce",
+			wantErr: true,
+		},
+		{
+			kind:    kindSecret,
+			ref:     "kitchen/sauces/secret-sauce/extra",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(string(tc.kind)+"/"+tc.ref, func(t *testing.T) {
+			c := &client{
+				k8sClient: clientset,
+				kind:      tc.kind,
+			}
+			defer c.Close()
+
+			actualVal, err := c.Resolve(context.Background(), tc.ref)
+			if err != nil && !tc.wantErr {
+				t.Errorf("Resolve() returned an error: %v", err)
+			}
+			if err == nil && tc.wantErr {
+				t.Error("Resolve() did not return an error")
+			}
+			if errors.Is(err, providers.ErrNotFound) != tc.wantNotFound {
+				t.Errorf("errors.Is(err, ErrNotFound) == %t, want %t", !tc.wantNotFound, tc.wantNotFound)
+			}
+			if actualVal != tc.wantVal {
+				t.Errorf("Resolve() == %#v, want %#v", actualVal, tc.wantVal)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+// Package providers contains types and errors shared by murmur's providers and
+// the resolution pipeline that uses them.
+package providers
+
+import (
+	"context"
+	"time"
+)
+
+// A Lease grants access to a secret for a limited time. Secret stores that
+// issue short-lived credentials, like Vault's dynamic secrets, attach a lease
+// to each secret they return. The lease must be renewed before it expires for
+// the secret to remain valid, and should be revoked once the secret is no
+// longer needed.
+//
+// A Lease remains usable after the provider that issued it is closed.
+type Lease interface {
+	// ID returns an identifier for the lease, suitable for logging.
+	ID() string
+
+	// TTL returns how long the lease was valid for when it was issued.
+	TTL() time.Duration
+
+	// Renewable reports whether the lease can be renewed.
+	Renewable() bool
+
+	// Renew extends the lease and returns how long it is now valid for.
+	Renew(ctx context.Context) (time.Duration, error)
+
+	// Revoke ends the lease. The secret it covers becomes invalid.
+	Revoke(ctx context.Context) error
+}
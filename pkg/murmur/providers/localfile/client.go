@@ -0,0 +1,100 @@
+package localfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/busser/murmur/pkg/murmur/providers"
+)
+
+type client struct {
+	// Whether to remove a single trailing newline from file contents.
+	trimNewline bool
+	// Whether to refuse reading files that any user can read.
+	strict bool
+}
+
+// New returns a client that reads secrets from local files, like those mounted
+// by Docker secrets or the Secrets Store CSI driver.
+func New() (*client, error) {
+	return NewWithOptions(Options{})
+}
+
+// Options configure a client.
+type Options struct {
+	// TrimNewline makes the client remove a trailing newline from the files it
+	// reads.
+	TrimNewline bool
+	// Strict makes the client refuse to read world-readable files.
+	Strict bool
+}
+
+// NewWithOptions works like New, but configures the client with the given
+// options.
+func NewWithOptions(opts Options) (*client, error) {
+	return &client{
+		trimNewline: opts.TrimNewline,
+		strict:      opts.Strict,
+	}, nil
+}
+
+func (c *client) Resolve(ctx context.Context, ref string) (string, error) {
+	path, err := parseRef(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid reference: %w", err)
+	}
+
+	if c.strict {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", readError(path, err)
+		}
+		if info.Mode().Perm()&0o004 != 0 {
+			return "", fmt.Errorf("refusing to read file %q: it is world-readable (mode %s)", path, info.Mode().Perm())
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", readError(path, err)
+	}
+
+	value := string(content)
+	if c.trimNewline {
+		value = trimTrailingNewline(value)
+	}
+
+	return value, nil
+}
+
+func readError(path string, err error) error {
+	err = fmt.Errorf("failed to read file %q: %w", path, err)
+	if errors.Is(err, fs.ErrNotExist) {
+		return providers.NotFound(err)
+	}
+	return err
+}
+
+func (c *client) Close() error {
+	// The client has no resources to free.
+	return nil
+}
+
+func parseRef(ref string) (path string, err error) {
+	if ref == "" {
+		return "", errors.New("path cannot be empty")
+	}
+
+	return ref, nil
+}
+
+func trimTrailingNewline(s string) string {
+	if strings.HasSuffix(s, "\r\n") {
+		return strings.TrimSuffix(s, "\r\n")
+	}
+	return strings.TrimSuffix(s, "\n")
+}
@@ -0,0 +1,121 @@
+package localfile_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/busser/murmur/pkg/murmur/providers"
+	"github.com/busser/murmur/pkg/murmur/providers/localfile"
+)
+
+func TestClient(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile := func(name, content string, perm os.FileMode) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), perm); err != nil {
+			t.Fatalf("could not write file: %v", err)
+		}
+		// The umask may have removed some permissions.
+		if err := os.Chmod(path, perm); err != nil {
+			t.Fatalf("could not change file mode: %v", err)
+		}
+		return path
+	}
+
+	private := writeFile("private", "szechuan\n", 0o600)
+	public := writeFile("public", "ketchup\n", 0o644)
+	windows := writeFile("windows", "mayonnaise\r\n", 0o600)
+	noNewline := writeFile("no-newline", "mustard", 0o600)
+	missing := filepath.Join(dir, "missing")
+
+	tt := []struct {
+		name         string
+		opts         localfile.Options
+		ref          string
+		wantVal      string
+		wantErr      bool
+		wantNotFound bool
+	}{
+		{
+			name:    "default",
+			ref:     private,
+			wantVal: "szechuan\n",
+		},
+		{
+			name:    "default with world-readable file",
+			ref:     public,
+			wantVal: "ketchup\n",
+		},
+		{
+			name:         "default with missing file",
+			ref:          missing,
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			name:    "trim newline",
+			opts:    localfile.Options{TrimNewline: true},
+			ref:     private,
+			wantVal: "szechuan",
+		},
+		{
+			name:    "trim windows newline",
+			opts:    localfile.Options{TrimNewline: true},
+			ref:     windows,
+			wantVal: "mayonnaise",
+		},
+		{
+			name:    "trim without newline",
+			opts:    localfile.Options{TrimNewline: true},
+			ref:     noNewline,
+			wantVal: "mustard",
+		},
+		{
+			name:    "strict",
+			opts:    localfile.Options{Strict: true},
+			ref:     private,
+			wantVal: "szechuan\n",
+		},
+		{
+			name:    "strict with world-readable file",
+			opts:    localfile.Options{Strict: true},
+			ref:     public,
+			wantErr: true,
+		},
+		{
+			name:         "strict with missing file",
+			opts:         localfile.Options{Strict: true},
+			ref:          missing,
+			wantErr:      true,
+			wantNotFound: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := localfile.NewWithOptions(tc.opts)
+			if err != nil {
+				t.Fatalf("NewWithOptions() returned an error: %v", err)
+			}
+			defer c.Close()
+
+			actualVal, err := c.Resolve(context.Background(), tc.ref)
+			if err != nil && !tc.wantErr {
+				t.Errorf("Resolve() returned an error: %v", err)
+			}
+			if err == nil && tc.wantErr {
+				t.Error("Resolve() did not return an error")
+			}
+			if errors.Is(err, providers.ErrNotFound) != tc.wantNotFound {
+				t.Errorf("errors.Is(err, ErrNotFound) == %t, want %t", !tc.wantNotFound, tc.wantNotFound)
+			}
+			if actualVal != tc.wantVal {
+				t.Errorf("Resolve() == %#v, want %#v", actualVal, tc.wantVal)
+			}
+		})
+	}
+}
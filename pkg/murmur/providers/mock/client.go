@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+
+	"github.com/busser/murmur/pkg/murmur/providers"
 )
 
 type client struct {
@@ -27,6 +29,9 @@ func (c *client) Resolve(ctx context.Context, ref string) (string, error) {
 	if ref == "FAIL" {
 		return "", ErrorFor(ref)
 	}
+	if ref == "NOT_FOUND" {
+		return "", providers.NotFound(ErrorFor(ref))
+	}
 
 	return ValueFor(ref), nil
 }
@@ -0,0 +1,301 @@
+package op
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/busser/murmur/pkg/murmur/providers"
+)
+
+type client struct {
+	httpClient *http.Client
+	host       string
+	token      string
+
+	mu       sync.Mutex // protects vaultIDs
+	vaultIDs map[string]string
+}
+
+// Options configure a client.
+type Options struct {
+	// InsecureSkipVerify disables verification of the Connect server's TLS
+	// certificate, for servers with self-signed certificates.
+	InsecureSkipVerify bool
+}
+
+// New returns a client that fetches secrets from 1Password, through the
+// 1Password Connect server at OP_CONNECT_HOST. The client authenticates with
+// the token in OP_CONNECT_TOKEN.
+func New() (*client, error) {
+	return NewWithOptions(Options{})
+}
+
+// NewWithOptions works like New, but configures the client with the given
+// options.
+func NewWithOptions(opts Options) (*client, error) {
+	host := os.Getenv("OP_CONNECT_HOST")
+	if host == "" {
+		return nil, errors.New("OP_CONNECT_HOST is not set")
+	}
+
+	token := os.Getenv("OP_CONNECT_TOKEN")
+	if token == "" {
+		return nil, errors.New("OP_CONNECT_TOKEN is not set")
+	}
+
+	httpClient := &http.Client{}
+	if opts.InsecureSkipVerify {
+		httpClient = providers.InsecureHTTPClient()
+	}
+
+	return &client{
+		httpClient: httpClient,
+		host:       strings.TrimSuffix(host, "/"),
+		token:      token,
+		vaultIDs:   make(map[string]string),
+	}, nil
+}
+
+func (c *client) Resolve(ctx context.Context, ref string) (string, error) {
+	vault, item, field, err := parseRef(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid reference: %w", err)
+	}
+
+	vaultID, err := c.vaultID(ctx, vault)
+	if err != nil {
+		return "", fmt.Errorf("failed to find vault %q: %w", vault, err)
+	}
+
+	itemID, err := c.itemID(ctx, vaultID, item)
+	if err != nil {
+		return "", fmt.Errorf("failed to find item %q in vault %q: %w", item, vault, err)
+	}
+
+	var fullItem struct {
+		Fields []struct {
+			ID    string `json:"id"`
+			Label string `json:"label"`
+			Value string `json:"value"`
+		} `json:"fields"`
+	}
+	err = c.get(ctx, "/v1/vaults/"+vaultID+"/items/"+itemID, nil, &fullItem)
+	if isNotFound(err) && isID(item) {
+		// The item's title looks like an ID, but is not one.
+		itemID, err = c.findByTitle(ctx, "/v1/vaults/"+vaultID+"/items", "title", item)
+		if err != nil {
+			return "", fmt.Errorf("failed to find item %q in vault %q: %w", item, vault, err)
+		}
+		err = c.get(ctx, "/v1/vaults/"+vaultID+"/items/"+itemID, nil, &fullItem)
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to get item %q in vault %q: %w", item, vault, err)
+		if isNotFound(err) {
+			return "", providers.NotFound(err)
+		}
+		return "", err
+	}
+
+	for _, f := range fullItem.Fields {
+		if f.ID == field || f.Label == field {
+			return f.Value, nil
+		}
+	}
+
+	return "", providers.NotFound(fmt.Errorf("item %q in vault %q has no field %q", item, vault, field))
+}
+
+func (c *client) Close() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// vaultID returns the ID of the vault with the given title or ID. Strings that
+// look like IDs are looked up by title if no vault has that ID. Vault IDs are
+// cached, since most references point to the same few vaults.
+func (c *client) vaultID(ctx context.Context, titleOrID string) (string, error) {
+	c.mu.Lock()
+	id, ok := c.vaultIDs[titleOrID]
+	c.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	id, err := c.findVault(ctx, titleOrID)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.vaultIDs[titleOrID] = id
+	c.mu.Unlock()
+
+	return id, nil
+}
+
+// findVault returns the ID of the vault with the given title or ID, without
+// caching it.
+func (c *client) findVault(ctx context.Context, titleOrID string) (string, error) {
+	if isID(titleOrID) {
+		var vault struct{}
+		err := c.get(ctx, "/v1/vaults/"+titleOrID, nil, &vault)
+		if !isNotFound(err) {
+			return titleOrID, err
+		}
+	}
+
+	return c.findByTitle(ctx, "/v1/vaults", "name", titleOrID)
+}
+
+// itemID returns the ID of the item with the given title or ID. Strings that
+// look like IDs are returned as-is: callers must look them up by title if no
+// item has that ID.
+func (c *client) itemID(ctx context.Context, vaultID, titleOrID string) (string, error) {
+	if isID(titleOrID) {
+		return titleOrID, nil
+	}
+
+	return c.findByTitle(ctx, "/v1/vaults/"+vaultID+"/items", "title", titleOrID)
+}
+
+// findByTitle lists the objects at path whose titleField matches title exactly
+// and returns the ID of the only match.
+func (c *client) findByTitle(ctx context.Context, path, titleField, title string) (string, error) {
+	query := url.Values{}
+	query.Set("filter", fmt.Sprintf("%s eq %q", titleField, title))
+
+	var objects []map[string]any
+	if err := c.get(ctx, path, query, &objects); err != nil {
+		return "", err
+	}
+
+	var ids []string
+	for _, o := range objects {
+		if o[titleField] == title {
+			id, _ := o["id"].(string)
+			ids = append(ids, id)
+		}
+	}
+
+	switch len(ids) {
+	case 0:
+		return "", providers.NotFound(errors.New("not found"))
+	case 1:
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("%d matches for title %q, use an ID instead", len(ids), title)
+	}
+}
+
+// get sends a GET request to the Connect API and decodes the response's body
+// into out.
+func (c *client) get(ctx context.Context, path string, query url.Values, out any) error {
+	u := c.host + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := newResponseError(resp)
+		if providers.TransientStatus(resp.StatusCode) {
+			return providers.Transient(err)
+		}
+		return err
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// isNotFound reports whether err comes from the Connect server responding
+// with status 404.
+func isNotFound(err error) bool {
+	var respErr *responseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
+
+// responseError is returned when the Connect server responds with an error
+// status code.
+type responseError struct {
+	StatusCode int
+	Message    string
+}
+
+func newResponseError(resp *http.Response) *responseError {
+	var body struct {
+		Message string `json:"message"`
+	}
+	// The body may be empty or not JSON, in which case we only report the
+	// status code.
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+
+	return &responseError{
+		StatusCode: resp.StatusCode,
+		Message:    body.Message,
+	}
+}
+
+func (e *responseError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("1Password Connect responded with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("1Password Connect responded with status %d: %s", e.StatusCode, e.Message)
+}
+
+func parseRef(ref string) (vault, item, field string, err error) {
+	vault, rest, found := strings.Cut(ref, "/")
+	if !found {
+		return "", "", "", errors.New("invalid syntax")
+	}
+
+	// Item titles may contain slashes, but vault names and field labels
+	// rarely do.
+	lastSlash := strings.LastIndex(rest, "/")
+	if lastSlash < 0 {
+		return "", "", "", errors.New("invalid syntax")
+	}
+	item, field = rest[:lastSlash], rest[lastSlash+1:]
+
+	if vault == "" || item == "" || field == "" {
+		return "", "", "", errors.New("vault, item, and field cannot be empty")
+	}
+
+	return vault, item, field, nil
+}
+
+// isID reports whether s looks like a 1Password ID. 1Password IDs are 26
+// characters long and only contain lowercase letters and digits. Titles can
+// look like IDs too, so this is only a hint of which lookup to try first.
+func isID(s string) bool {
+	if len(s) != 26 {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
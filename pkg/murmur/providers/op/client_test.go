@@ -0,0 +1,379 @@
+package op_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/busser/murmur/pkg/murmur/providers"
+	"github.com/busser/murmur/pkg/murmur/providers/op"
+)
+
+func Example() {
+	c, err := op.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ref := "Kitchen/Secret Sauce/recipe"
+	val, err := c.Resolve(context.Background(), ref)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("The secret sauce is", val)
+}
+
+const (
+	testToken = "test-token"
+
+	kitchenVaultID = "k1tchenvau1t0000000000000a"
+	sauceItemID    = "secretsauce000000000000000"
+	bbqItemID      = "bbqsauce000000000000000000"
+	cellarVaultID  = "ce11arvau1t000000000000000"
+
+	// These titles look like IDs.
+	condimentsVaultTitle = "saucesandcondimentsvault01"
+	grandmasItemTitle    = "grandmassecretsaucerecipe1"
+)
+
+// newFakeConnect returns a server that mimics a small subset of the 1Password
+// Connect API. It serves a "Kitchen" vault with two items titled "Secret Sauce"
+// and "BBQ/Sauces", and a "Pantry" vault with two items both titled "Spice".
+// Listing the items of the "Cellar" vault fails as if the server were
+// overloaded. Some vaults and items have titles that look like IDs.
+func newFakeConnect(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	type field struct {
+		ID    string `json:"id"`
+		Label string `json:"label"`
+		Value string `json:"value"`
+	}
+	type item struct {
+		ID     string  `json:"id"`
+		Title  string  `json:"title"`
+		Fields []field `json:"fields"`
+	}
+	type vault struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+
+	vaults := []vault{
+		{ID: kitchenVaultID, Name: "Kitchen"},
+		{ID: "pantryvau1t000000000000000", Name: "Pantry"},
+		{ID: cellarVaultID, Name: "Cellar"},
+		{ID: "condimentsvau1t00000000000", Name: condimentsVaultTitle},
+	}
+	items := map[string][]item{
+		kitchenVaultID: {
+			{
+				ID:    sauceItemID,
+				Title: "Secret Sauce",
+				Fields: []field{
+					{ID: "recipe", Label: "recipe", Value: "szechuan"},
+					{ID: "password", Label: "chef's password", Value: "ketchup"},
+				},
+			},
+			{
+				ID:    bbqItemID,
+				Title: "BBQ/Sauces",
+				Fields: []field{
+					{ID: "recipe", Label: "recipe", Value: "smoky"},
+				},
+			},
+			{
+				ID:    "grandmasitem00000000000000",
+				Title: grandmasItemTitle,
+				Fields: []field{
+					{ID: "recipe", Label: "recipe", Value: "sweet & sour"},
+				},
+			},
+		},
+		"condimentsvau1t00000000000": {
+			{
+				ID:    "ketchupitem000000000000000",
+				Title: "Ketchup",
+				Fields: []field{
+					{ID: "recipe", Label: "recipe", Value: "tomato"},
+				},
+			},
+		},
+		"pantryvau1t000000000000000": {
+			{ID: "spice100000000000000000000", Title: "Spice"},
+			{ID: "spice200000000000000000000", Title: "Spice"},
+		},
+	}
+
+	reply := func(w http.ResponseWriter, status int, body any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	}
+
+	// titleFilter extracts the expected value from filters like `title eq "x"`.
+	titleFilter := func(r *http.Request, field string) (string, bool) {
+		filter := r.URL.Query().Get("filter")
+		if filter == "" {
+			return "", false
+		}
+		var title string
+		if _, err := fmt.Sscanf(filter, field+" eq %q", &title); err != nil {
+			return "", false
+		}
+		return title, true
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /v1/vaults", func(w http.ResponseWriter, r *http.Request) {
+		name, filtered := titleFilter(r, "name")
+		var matches []vault
+		for _, v := range vaults {
+			if !filtered || v.Name == name {
+				matches = append(matches, v)
+			}
+		}
+		reply(w, http.StatusOK, matches)
+	})
+
+	mux.HandleFunc("GET /v1/vaults/{vault}", func(w http.ResponseWriter, r *http.Request) {
+		for _, v := range vaults {
+			if v.ID == r.PathValue("vault") {
+				reply(w, http.StatusOK, v)
+				return
+			}
+		}
+		reply(w, http.StatusNotFound, map[string]any{"status": 404, "message": "Invalid Vault UUID"})
+	})
+
+	mux.HandleFunc("GET /v1/vaults/{vault}/items", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("vault") == cellarVaultID {
+			reply(w, http.StatusServiceUnavailable, map[string]any{"status": 503, "message": "try again later"})
+			return
+		}
+		vaultItems, ok := items[r.PathValue("vault")]
+		if !ok {
+			reply(w, http.StatusNotFound, map[string]any{"status": 404, "message": "Invalid Vault UUID"})
+			return
+		}
+		title, filtered := titleFilter(r, "title")
+		var matches []item
+		for _, i := range vaultItems {
+			if !filtered || i.Title == title {
+				matches = append(matches, item{ID: i.ID, Title: i.Title})
+			}
+		}
+		reply(w, http.StatusOK, matches)
+	})
+
+	mux.HandleFunc("GET /v1/vaults/{vault}/items/{item}", func(w http.ResponseWriter, r *http.Request) {
+		for _, i := range items[r.PathValue("vault")] {
+			if i.ID == r.PathValue("item") {
+				reply(w, http.StatusOK, i)
+				return
+			}
+		}
+		reply(w, http.StatusNotFound, map[string]any{"status": 404, "message": "item not found"})
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testToken {
+			reply(w, http.StatusUnauthorized, map[string]any{"status": 401, "message": "Invalid token signature"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestClient(t *testing.T) {
+	srv := newFakeConnect(t)
+
+	t.Setenv("OP_CONNECT_HOST", srv.URL)
+	t.Setenv("OP_CONNECT_TOKEN", testToken)
+
+	client, err := op.New()
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+	defer client.Close()
+
+	tt := []struct {
+		ref           string
+		wantVal       string
+		wantErr       bool
+		wantNotFound  bool
+		wantTransient bool
+	}{
+		// References by title.
+		{
+			ref:     "Kitchen/Secret Sauce/recipe",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "Kitchen/Secret Sauce/chef's password",
+			wantVal: "ketchup",
+		},
+		{
+			ref:     "Kitchen/BBQ/Sauces/recipe",
+			wantVal: "smoky",
+		},
+
+		// References by ID.
+		{
+			ref:     kitchenVaultID + "/" + sauceItemID + "/recipe",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "Kitchen/" + sauceItemID + "/password",
+			wantVal: "ketchup",
+		},
+
+		// Titles that look like IDs.
+		{
+			ref:     condimentsVaultTitle + "/Ketchup/recipe",
+			wantVal: "tomato",
+		},
+		{
+			ref:     "Kitchen/" + grandmasItemTitle + "/recipe",
+			wantVal: "sweet & sour",
+		},
+		{
+			ref:     kitchenVaultID + "/" + grandmasItemTitle + "/recipe",
+			wantVal: "sweet & sour",
+		},
+
+		// Missing or ambiguous objects.
+		{
+			ref:          "Garage/Secret Sauce/recipe",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			ref:          "Kitchen/Mayonnaise/recipe",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			ref:          "Kitchen/Secret Sauce/origin",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			ref:          "missingvau1t00000000000000/Secret Sauce/recipe",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			ref:          "Kitchen/missingitem000000000000000/recipe",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			ref:     "Pantry/Spice/name",
+			wantErr: true,
+		},
+
+		// Server errors.
+		{
+			ref:           "Cellar/Wine/vintage",
+			wantErr:       true,
+			wantTransient: true,
+		},
+
+		// Invalid references.
+		{
+			ref:     "Kitchen/Secret Sauce",
+			wantErr: true,
+		},
+		{
+			ref:     "Kitchen//recipe",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.ref, func(t *testing.T) {
+			actualVal, err := client.Resolve(context.Background(), tc.ref)
+			if err != nil && !tc.wantErr {
+				t.Errorf("Resolve() returned an error: %v", err)
+			}
+			if err == nil && tc.wantErr {
+				t.Error("Resolve() did not return an error")
+			}
+			if errors.Is(err, providers.ErrNotFound) != tc.wantNotFound {
+				t.Errorf("errors.Is(err, ErrNotFound) == %t, want %t", !tc.wantNotFound, tc.wantNotFound)
+			}
+			if errors.Is(err, providers.ErrTransient) != tc.wantTransient {
+				t.Errorf("errors.Is(err, ErrTransient) == %t, want %t", !tc.wantTransient, tc.wantTransient)
+			}
+			if actualVal != tc.wantVal {
+				t.Errorf("Resolve() == %#v, want %#v", actualVal, tc.wantVal)
+			}
+		})
+	}
+}
+
+func TestClientWrongToken(t *testing.T) {
+	srv := newFakeConnect(t)
+
+	t.Setenv("OP_CONNECT_HOST", srv.URL)
+	t.Setenv("OP_CONNECT_TOKEN", "wrong-token")
+
+	client, err := op.New()
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.Resolve(context.Background(), "Kitchen/Secret Sauce/recipe")
+	if err == nil {
+		t.Fatal("Resolve() did not return an error")
+	}
+	if !strings.Contains(err.Error(), "401") {
+		t.Errorf("Resolve() error %q should mention the status code", err)
+	}
+}
+
+func TestClientInsecureSkipVerify(t *testing.T) {
+	srv := httptest.NewTLSServer(newFakeConnect(t).Config.Handler)
+	t.Cleanup(srv.Close)
+
+	t.Setenv("OP_CONNECT_HOST", srv.URL)
+	t.Setenv("OP_CONNECT_TOKEN", testToken)
+
+	// The server's certificate is self-signed.
+	client, err := op.New()
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.Resolve(context.Background(), "Kitchen/Secret Sauce/recipe"); err == nil {
+		t.Error("Resolve() did not return an error")
+	}
+
+	client, err = op.NewWithOptions(op.Options{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("NewWithOptions() returned an error: %v", err)
+	}
+	defer client.Close()
+
+	val, err := client.Resolve(context.Background(), "Kitchen/Secret Sauce/recipe")
+	if err != nil {
+		t.Fatalf("Resolve() returned an error: %v", err)
+	}
+	if val != "szechuan" {
+		t.Errorf("Resolve() == %#v, want %#v", val, "szechuan")
+	}
+}
@@ -0,0 +1,248 @@
+// Package plugin implements a provider backed by an external executable, so
+// that murmur can fetch secrets from stores it has no built-in support for.
+//
+// Murmur starts the executable once per resolution and speaks a JSON protocol
+// with it over stdio. Murmur writes one request per line to the plugin's
+// stdin:
+//
+//	{"id": 1, "ref": "database/password"}
+//
+// The plugin writes one response per request to its stdout, in any order:
+//
+//	{"id": 1, "value": "szechuan"}
+//	{"id": 2, "error": "secret not found"}
+//
+// A response with a non-empty error is a failed resolution. If the secret does
+// not exist, the plugin should say so, so that murmur can fall back to a
+// default value if the query has one:
+//
+//	{"id": 3, "error": "secret not found", "notFound": true}
+//
+// If the failure may go away on its own, like when the store throttles
+// requests, the plugin should say so too, so that murmur tries again:
+//
+//	{"id": 4, "error": "too many requests", "transient": true}
+//
+// Murmur may send several requests before reading any response, and closes the
+// plugin's stdin once it needs no more secrets; the plugin should then exit.
+// Anything the plugin writes to stderr is forwarded to murmur's stderr.
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/busser/murmur/pkg/murmur/providers"
+)
+
+// Modified during testing.
+var closeTimeout = 5 * time.Second
+
+type client struct {
+	path string
+	cmd  *exec.Cmd
+
+	writeMu sync.Mutex // serializes writes to stdin
+	stdin   io.WriteCloser
+
+	mu      sync.Mutex // protects all fields below
+	nextID  uint64
+	pending map[uint64]chan response
+	err     error // set once the plugin stops responding
+
+	exited chan struct{} // closed once the response reader returns
+}
+
+type request struct {
+	ID  uint64 `json:"id"`
+	Ref string `json:"ref"`
+}
+
+type response struct {
+	ID    uint64 `json:"id"`
+	Value string `json:"value"`
+	Error string `json:"error,omitempty"`
+
+	NotFound  bool `json:"notFound,omitempty"`
+	Transient bool `json:"transient,omitempty"`
+}
+
+// Options configure a client.
+type Options struct {
+	// Env holds environment variables to set for the plugin, in "KEY=value"
+	// form, in addition to murmur's own environment.
+	Env []string
+}
+
+// New starts the plugin executable at path and returns a client that fetches
+// secrets from it.
+func New(path string) (*client, error) {
+	return NewWithOptions(path, Options{})
+}
+
+// NewWithOptions works like New, but configures the client with the given
+// options.
+func NewWithOptions(path string, opts Options) (*client, error) {
+	cmd := exec.Command(path)
+	cmd.Stderr = os.Stderr
+	if len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin's stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin's stdout: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %q: %w", path, err)
+	}
+
+	c := &client{
+		path:    path,
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[uint64]chan response),
+		exited:  make(chan struct{}),
+	}
+
+	go c.readResponses(stdout)
+
+	return c, nil
+}
+
+func (c *client) Resolve(ctx context.Context, ref string) (string, error) {
+	respCh := make(chan response, 1)
+
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return "", err
+	}
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = respCh
+	c.mu.Unlock()
+
+	if err := c.send(request{ID: id, Ref: ref}); err != nil {
+		c.forget(id)
+		return "", fmt.Errorf("failed to send request to plugin: %w", err)
+	}
+
+	select {
+	case resp, ok := <-respCh:
+		if !ok {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return "", c.err
+		}
+		if resp.Error != "" {
+			err := errors.New(resp.Error)
+			switch {
+			case resp.NotFound:
+				return "", providers.NotFound(err)
+			case resp.Transient:
+				return "", providers.Transient(err)
+			default:
+				return "", err
+			}
+		}
+		return resp.Value, nil
+	case <-ctx.Done():
+		c.forget(id)
+		return "", ctx.Err()
+	}
+}
+
+func (c *client) Close() error {
+	// Closing stdin tells the plugin that no more requests are coming.
+	c.writeMu.Lock()
+	_ = c.stdin.Close()
+	c.writeMu.Unlock()
+
+	select {
+	case <-c.exited:
+	case <-time.After(closeTimeout):
+		_ = c.cmd.Process.Kill()
+		<-c.exited
+	}
+
+	if err := c.cmd.Wait(); err != nil {
+		return fmt.Errorf("plugin %q: %w", c.path, err)
+	}
+
+	return nil
+}
+
+func (c *client) send(req request) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	// The encoder writes a newline after each request.
+	return json.NewEncoder(c.stdin).Encode(req)
+}
+
+// forget stops waiting for the response to the request with the given ID.
+func (c *client) forget(id uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.pending, id)
+}
+
+// readResponses reads responses from the plugin's stdout and routes each one to
+// the Resolve call waiting for it. Once the plugin stops responding, all
+// pending and future calls fail.
+func (c *client) readResponses(stdout io.Reader) {
+	defer close(c.exited)
+
+	dec := json.NewDecoder(stdout)
+	for {
+		var resp response
+		if err := dec.Decode(&resp); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("plugin exited")
+			} else {
+				// The plugin does not follow the protocol, so there is no point
+				// in keeping it around.
+				_ = c.cmd.Process.Kill()
+				err = fmt.Errorf("invalid response from plugin: %w", err)
+			}
+			c.fail(err)
+			return
+		}
+
+		c.mu.Lock()
+		respCh, ok := c.pending[resp.ID]
+		delete(c.pending, resp.ID)
+		c.mu.Unlock()
+
+		// Responses to requests nobody waits for anymore are dropped.
+		if ok {
+			respCh <- resp
+		}
+	}
+}
+
+func (c *client) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.err = err
+	for id, respCh := range c.pending {
+		close(respCh)
+		delete(c.pending, id)
+	}
+}
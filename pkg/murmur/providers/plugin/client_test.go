@@ -0,0 +1,228 @@
+package plugin_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/busser/murmur/pkg/murmur/providers"
+	"github.com/busser/murmur/pkg/murmur/providers/plugin"
+)
+
+func Example() {
+	c, err := plugin.New("/usr/local/bin/murmur-provider-mycorp")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer c.Close()
+
+	ref := "secret-sauce"
+	val, err := c.Resolve(context.Background(), ref)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("The secret sauce is", val)
+}
+
+// When this variable is set, the test binary acts as a plugin instead of
+// running tests. This way, tests can use the test binary as a plugin.
+const helperEnv = "MURMUR_PLUGIN_TEST_HELPER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(helperEnv) != "" {
+		runFakePlugin()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runFakePlugin implements the plugin protocol. It knows a few secrets, and
+// misbehaves in different ways for some references.
+func runFakePlugin() {
+	secrets := map[string]string{
+		"secret-sauce": "szechuan",
+		"old-sauce":    "ketchup",
+	}
+
+	var mu sync.Mutex // serializes writes to stdout
+	enc := json.NewEncoder(os.Stdout)
+	reply := func(resp map[string]any) {
+		mu.Lock()
+		defer mu.Unlock()
+		_ = enc.Encode(resp)
+	}
+
+	var slow []uint64 // requests answered once stdin is closed
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req struct {
+			ID  uint64 `json:"id"`
+			Ref string `json:"ref"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid request:", err)
+			os.Exit(1)
+		}
+
+		switch req.Ref {
+		case "crash":
+			os.Exit(1)
+		case "garbage":
+			fmt.Println("this is not JSON")
+		case "slow":
+			slow = append(slow, req.ID)
+		case "throttled":
+			reply(map[string]any{"id": req.ID, "error": "too many requests", "transient": true})
+		default:
+			value, ok := secrets[req.Ref]
+			if !ok {
+				reply(map[string]any{"id": req.ID, "error": fmt.Sprintf("secret %q not found", req.Ref), "notFound": true})
+				continue
+			}
+			reply(map[string]any{"id": req.ID, "value": value})
+		}
+	}
+
+	for _, id := range slow {
+		reply(map[string]any{"id": id, "value": "worth the wait"})
+	}
+}
+
+type provider interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+	Close() error
+}
+
+func newTestClient(t *testing.T) provider {
+	t.Helper()
+
+	t.Setenv(helperEnv, "1")
+
+	c, err := plugin.New(os.Args[0])
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+
+	return c
+}
+
+func TestClient(t *testing.T) {
+	client := newTestClient(t)
+
+	tt := []struct {
+		ref           string
+		wantVal       string
+		wantErr       bool
+		wantNotFound  bool
+		wantTransient bool
+	}{
+		{
+			ref:     "secret-sauce",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "old-sauce",
+			wantVal: "ketchup",
+		},
+		{
+			ref:          "mayonnaise",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			ref:           "throttled",
+			wantErr:       true,
+			wantTransient: true,
+		},
+	}
+
+	// Requests run in parallel, so the plugin receives several requests before
+	// responding to any of them.
+	t.Run("group", func(t *testing.T) {
+		for _, tc := range tt {
+			t.Run(tc.ref, func(t *testing.T) {
+				t.Parallel()
+
+				actualVal, err := client.Resolve(context.Background(), tc.ref)
+				if err != nil && !tc.wantErr {
+					t.Errorf("Resolve() returned an error: %v", err)
+				}
+				if err == nil && tc.wantErr {
+					t.Error("Resolve() did not return an error")
+				}
+				if errors.Is(err, providers.ErrNotFound) != tc.wantNotFound {
+					t.Errorf("errors.Is(err, ErrNotFound) == %t, want %t", !tc.wantNotFound, tc.wantNotFound)
+				}
+				if errors.Is(err, providers.ErrTransient) != tc.wantTransient {
+					t.Errorf("errors.Is(err, ErrTransient) == %t, want %t", !tc.wantTransient, tc.wantTransient)
+				}
+				if actualVal != tc.wantVal {
+					t.Errorf("Resolve() == %#v, want %#v", actualVal, tc.wantVal)
+				}
+			})
+		}
+	})
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() returned an error: %v", err)
+	}
+}
+
+func TestClientCanceled(t *testing.T) {
+	client := newTestClient(t)
+
+	// The plugin only answers slow requests once murmur closes its stdin, so
+	// this request never completes before the context is canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.Resolve(ctx, "slow")
+	if err == nil {
+		t.Error("Resolve() did not return an error")
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() returned an error: %v", err)
+	}
+}
+
+func TestClientMisbehavingPlugin(t *testing.T) {
+	for _, ref := range []string{"crash", "garbage"} {
+		t.Run(ref, func(t *testing.T) {
+			client := newTestClient(t)
+
+			_, err := client.Resolve(context.Background(), ref)
+			if err == nil {
+				t.Fatal("Resolve() did not return an error")
+			}
+
+			// Once the plugin misbehaves, all requests fail.
+			_, err = client.Resolve(context.Background(), "secret-sauce")
+			if err == nil {
+				t.Fatal("Resolve() did not return an error")
+			}
+
+			// The plugin did not exit cleanly.
+			_ = client.Close()
+		})
+	}
+}
+
+func TestClientMissingExecutable(t *testing.T) {
+	_, err := plugin.New("/does/not/exist/murmur-provider-mycorp")
+	if err == nil {
+		t.Fatal("New() did not return an error")
+	}
+	if !strings.Contains(err.Error(), "murmur-provider-mycorp") {
+		t.Errorf("New() error %q should mention the plugin's path", err)
+	}
+}
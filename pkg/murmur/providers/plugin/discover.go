@@ -0,0 +1,105 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ExecutablePrefix is the prefix of the names of plugin executables. A plugin
+// named "murmur-provider-mycorp" provides secrets for queries like
+// "mycorp:foo".
+const ExecutablePrefix = "murmur-provider-"
+
+// Discover returns the paths of all available plugin executables, keyed by
+// provider ID.
+//
+// Plugins listed in the MURMUR_PROVIDER_PLUGINS environment variable, as
+// comma-separated id=path pairs, take precedence. Other plugins are found by
+// looking for executables whose name starts with ExecutablePrefix in the
+// directories of PATH. As with any command, the first match on PATH wins.
+func Discover() (map[string]string, error) {
+	plugins := make(map[string]string)
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			// Like the shell, ignore directories on PATH we cannot read.
+			continue
+		}
+		for _, entry := range entries {
+			id, ok := providerID(entry.Name())
+			if !ok {
+				continue
+			}
+			if _, exists := plugins[id]; exists {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if !isExecutable(path) {
+				continue
+			}
+			plugins[id] = path
+		}
+	}
+
+	configured, err := parsePluginList(os.Getenv("MURMUR_PROVIDER_PLUGINS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MURMUR_PROVIDER_PLUGINS: %w", err)
+	}
+	for id, path := range configured {
+		plugins[id] = path
+	}
+
+	return plugins, nil
+}
+
+// providerID returns the provider ID of the plugin with the given executable
+// name, and whether the name is that of a plugin at all.
+func providerID(name string) (string, bool) {
+	id, ok := strings.CutPrefix(name, ExecutablePrefix)
+	if !ok {
+		return "", false
+	}
+	if runtime.GOOS == "windows" {
+		id = strings.TrimSuffix(id, ".exe")
+	}
+	return id, id != ""
+}
+
+func isExecutable(path string) bool {
+	// Follow symbolic links, since package managers often install
+	// executables that way.
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	return info.Mode().Perm()&0o111 != 0
+}
+
+// parsePluginList parses a comma-separated list of id=path pairs.
+func parsePluginList(s string) (map[string]string, error) {
+	plugins := make(map[string]string)
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, path, found := strings.Cut(entry, "=")
+		if !found || id == "" || path == "" {
+			return nil, fmt.Errorf("%q is not of the form id=path", entry)
+		}
+		plugins[id] = path
+	}
+
+	return plugins, nil
+}
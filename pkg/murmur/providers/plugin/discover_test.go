@@ -0,0 +1,60 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiscover(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+
+	writeFile := func(path string, perm os.FileMode) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"), perm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile(filepath.Join(first, "murmur-provider-mycorp"), 0o755)
+	writeFile(filepath.Join(first, "murmur-provider-notexec"), 0o644)
+	writeFile(filepath.Join(first, "unrelated"), 0o755)
+	writeFile(filepath.Join(second, "murmur-provider-mycorp"), 0o755)
+	writeFile(filepath.Join(second, "murmur-provider-legacy"), 0o755)
+	if err := os.Mkdir(filepath.Join(second, "murmur-provider-dir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("PATH", first+string(filepath.ListSeparator)+second+string(filepath.ListSeparator)+"/does/not/exist")
+	t.Setenv("MURMUR_PROVIDER_PLUGINS", "legacy=/opt/legacy/plugin, extra=/opt/extra/plugin")
+
+	plugins, err := Discover()
+	if err != nil {
+		t.Fatalf("Discover() returned an error: %v", err)
+	}
+
+	want := map[string]string{
+		"mycorp": filepath.Join(first, "murmur-provider-mycorp"),
+		"legacy": "/opt/legacy/plugin",
+		"extra":  "/opt/extra/plugin",
+	}
+	if diff := cmp.Diff(want, plugins); diff != "" {
+		t.Errorf("Discover() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiscoverInvalidConfig(t *testing.T) {
+	t.Setenv("PATH", "")
+
+	for _, config := range []string{"mycorp", "=/opt/plugin", "mycorp="} {
+		t.Run(config, func(t *testing.T) {
+			t.Setenv("MURMUR_PROVIDER_PLUGINS", config)
+
+			if _, err := Discover(); err == nil {
+				t.Error("Discover() did not return an error")
+			}
+		})
+	}
+}
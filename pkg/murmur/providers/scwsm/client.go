@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 
+	"github.com/busser/murmur/pkg/murmur/providers"
 	"github.com/google/uuid"
 	scwsecret "github.com/scaleway/scaleway-sdk-go/api/secret/v1beta1"
 	"github.com/scaleway/scaleway-sdk-go/scw"
@@ -15,11 +17,57 @@ type client struct {
 	scwClient *scw.Client
 }
 
-// New returns a client that fetches secrets from Google Secret Manager.
+// Options configure a client. The zero value uses the active profile of the
+// Scaleway config file, overridden by environment variables, like the scw CLI.
+type Options struct {
+	// Profile selects a profile of the Scaleway config file other than the
+	// active one.
+	Profile string
+	// Region is the region of secrets whose reference has none, like
+	// "fr-par".
+	Region string
+	// Endpoint overrides the URL of the Scaleway API.
+	Endpoint string
+	// InsecureSkipVerify disables verification of the API's TLS certificate,
+	// for mock servers that serve self-signed certificates. It has no effect if
+	// HTTPClient is set.
+	InsecureSkipVerify bool
+	// HTTPClient sends requests to the API.
+	HTTPClient *http.Client
+}
+
+// New returns a client that fetches secrets from Scaleway Secret Manager.
 func New() (*client, error) {
-	profile := loadDefaultProfile()
+	return NewWithOptions(Options{})
+}
+
+// NewWithOptions works like New, but configures the client with the given
+// options.
+func NewWithOptions(opts Options) (*client, error) {
+	profile, err := loadProfile(opts.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	scwOpts := []scw.ClientOption{scw.WithProfile(profile)}
+	if opts.Region != "" {
+		region, err := scw.ParseRegion(opts.Region)
+		if err != nil {
+			return nil, fmt.Errorf("invalid region: %w", err)
+		}
+		scwOpts = append(scwOpts, scw.WithDefaultRegion(region))
+	}
+	if opts.Endpoint != "" {
+		scwOpts = append(scwOpts, scw.WithAPIURL(opts.Endpoint))
+	}
+	if opts.HTTPClient == nil && opts.InsecureSkipVerify {
+		opts.HTTPClient = providers.InsecureHTTPClient()
+	}
+	if opts.HTTPClient != nil {
+		scwOpts = append(scwOpts, scw.WithHTTPClient(opts.HTTPClient))
+	}
 
-	c, err := scw.NewClient(scw.WithProfile(profile))
+	c, err := scw.NewClient(scwOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to setup client: %w", err)
 	}
@@ -41,22 +89,40 @@ func (c *client) Resolve(ctx context.Context, ref string) (string, error) {
 	return c.resolveByName(ctx, region, name, revision)
 }
 
-func loadDefaultProfile() *scw.Profile {
-	return scw.MergeProfiles(loadConfigProfile(), scw.LoadEnvProfile())
+// loadProfile returns the profile with the given name from the Scaleway config
+// file, or the active profile if name is empty, overridden by environment
+// variables.
+func loadProfile(name string) (*scw.Profile, error) {
+	profile, err := loadConfigProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	return scw.MergeProfiles(profile, scw.LoadEnvProfile()), nil
 }
 
-func loadConfigProfile() *scw.Profile {
+func loadConfigProfile(name string) (*scw.Profile, error) {
 	config, err := scw.LoadConfig()
 	if err != nil {
-		return &scw.Profile{}
+		if name != "" {
+			return nil, fmt.Errorf("failed to load config for profile %q: %w", name, err)
+		}
+		return &scw.Profile{}, nil
+	}
+
+	if name != "" {
+		profile, err := config.GetProfile(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load profile %q: %w", name, err)
+		}
+		return profile, nil
 	}
 
 	profile, err := config.GetActiveProfile()
 	if err != nil {
-		return &scw.Profile{}
+		return &scw.Profile{}, nil
 	}
 
-	return profile
+	return profile, nil
 }
 
 func (c *client) resolveByID(ctx context.Context, region scw.Region, id, revision string) (string, error) {
@@ -65,9 +131,16 @@ func (c *client) resolveByID(ctx context.Context, region scw.Region, id, revisio
 		SecretID: id,
 		Revision: revision,
 	}
-	resp, err := scwsecret.NewAPI(c.scwClient).AccessSecretVersion(req)
+	resp, err := scwsecret.NewAPI(c.scwClient).AccessSecretVersion(req, scw.WithContext(ctx))
 	if err != nil {
-		return "", fmt.Errorf("failed to access secret (region: %q, id: %q, revision: %q): %w", region, id, revision, err)
+		err = fmt.Errorf("failed to access secret (region: %q, id: %q, revision: %q): %w", region, id, revision, err)
+		if isNotFound(err) {
+			return "", providers.NotFound(err)
+		}
+		if isTransient(err) {
+			return "", providers.Transient(err)
+		}
+		return "", err
 	}
 
 	return string(resp.Data), nil
@@ -79,14 +152,38 @@ func (c *client) resolveByName(ctx context.Context, region scw.Region, name, rev
 		SecretName: name,
 		Revision:   revision,
 	}
-	resp, err := scwsecret.NewAPI(c.scwClient).AccessSecretVersionByPath(req)
+	resp, err := scwsecret.NewAPI(c.scwClient).AccessSecretVersionByPath(req, scw.WithContext(ctx))
 	if err != nil {
-		return "", fmt.Errorf("failed to access secret (region: %q, name: %q, revision: %q): %w", region, name, revision, err)
+		err = fmt.Errorf("failed to access secret (region: %q, name: %q, revision: %q): %w", region, name, revision, err)
+		if isNotFound(err) {
+			return "", providers.NotFound(err)
+		}
+		if isTransient(err) {
+			return "", providers.Transient(err)
+		}
+		return "", err
 	}
 
 	return string(resp.Data), nil
 }
 
+// isNotFound reports whether err means that the secret does not exist.
+func isNotFound(err error) bool {
+	var notFound *scw.ResourceNotFoundError
+	if errors.As(err, &notFound) {
+		return true
+	}
+
+	var respErr *scw.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
+
+// isTransient reports whether err is a throttling or server error.
+func isTransient(err error) bool {
+	var respErr *scw.ResponseError
+	return errors.As(err, &respErr) && providers.TransientStatus(respErr.StatusCode)
+}
+
 func (c *client) Close() error {
 	// No need to close the client.
 	return nil
@@ -0,0 +1,187 @@
+package scwsm_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/busser/murmur/pkg/murmur/providers/scwsm"
+)
+
+type fakeSecret struct {
+	id   string
+	name string
+	// Values of the secret's revisions, oldest first.
+	revisions []string
+}
+
+// fakeSecretManager serves the parts of the Secret Manager API the client
+// uses, like a Scaleway mock server does.
+type fakeSecretManager struct {
+	region  string
+	secrets []fakeSecret
+}
+
+func (f *fakeSecretManager) handler() http.Handler {
+	mux := http.NewServeMux()
+	prefix := "/secret-manager/v1beta1/regions/" + f.region
+
+	mux.HandleFunc("GET "+prefix+"/secrets/{id}/versions/{revision}/access", func(w http.ResponseWriter, r *http.Request) {
+		f.access(w, r.PathValue("revision"), func(s fakeSecret) bool {
+			return s.id == r.PathValue("id")
+		})
+	})
+	mux.HandleFunc("GET "+prefix+"/secrets-by-path/versions/{revision}/access", func(w http.ResponseWriter, r *http.Request) {
+		f.access(w, r.PathValue("revision"), func(s fakeSecret) bool {
+			return s.name == r.URL.Query().Get("secret_name")
+		})
+	})
+
+	return mux
+}
+
+func (f *fakeSecretManager) access(w http.ResponseWriter, revision string, match func(fakeSecret) bool) {
+	w.Header().Set("Content-Type", "application/json")
+
+	notFound := func() {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{
+			"type":    "not_found",
+			"message": "resource is not found",
+		})
+	}
+
+	for _, s := range f.secrets {
+		if !match(s) {
+			continue
+		}
+
+		n := len(s.revisions)
+		if revision != "latest" {
+			var err error
+			n, err = strconv.Atoi(revision)
+			if err != nil || n < 1 || n > len(s.revisions) {
+				notFound()
+				return
+			}
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"secret_id": s.id,
+			"revision":  n,
+			"data":      []byte(s.revisions[n-1]),
+		})
+		return
+	}
+
+	notFound()
+}
+
+// TestClientEmulated runs the same tests as TestClient, against a fake Secret
+// Manager API served over TLS with a self-signed certificate instead of
+// Scaleway, so that it needs neither network access nor credentials.
+func TestClientEmulated(t *testing.T) {
+	// The fake mirrors the secrets that Terraform creates for TestClient.
+	fake := &fakeSecretManager{
+		region: "fr-par",
+		secrets: []fakeSecret{
+			{
+				id:        "3f34b83f-47a6-4344-bcd4-b63721481cd3",
+				name:      "secret-sauce",
+				revisions: []string{"ketchup", "szechuan"},
+			},
+		},
+	}
+	srv := httptest.NewTLSServer(fake.handler())
+	t.Cleanup(srv.Close)
+
+	// Ignore any Scaleway config file on the machine running the tests.
+	t.Setenv("SCW_CONFIG_PATH", filepath.Join(t.TempDir(), "config.yaml"))
+
+	client, err := scwsm.NewWithOptions(scwsm.Options{
+		Region:             "fr-par",
+		Endpoint:           srv.URL,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		t.Fatalf("NewWithOptions() returned an error: %v", err)
+	}
+
+	tt := []struct {
+		ref     string
+		wantVal string
+		wantErr bool
+	}{
+		{
+			ref:     "secret-sauce",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "secret-sauce#2",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "secret-sauce#1",
+			wantVal: "ketchup",
+		},
+		{
+			ref:     "fr-par/secret-sauce",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "fr-par/secret-sauce#1",
+			wantVal: "ketchup",
+		},
+		{
+			ref:     "3f34b83f-47a6-4344-bcd4-b63721481cd3",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "3f34b83f-47a6-4344-bcd4-b63721481cd3#1",
+			wantVal: "ketchup",
+		},
+		{
+			ref:     "fr-par/3f34b83f-47a6-4344-bcd4-b63721481cd3#2",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "does-not-exist",
+			wantErr: true,
+		},
+		{
+			ref:     "fr-par/does-not-exist",
+			wantErr: true,
+		},
+		{
+			ref:     "fr-par/does-not-exist#123",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.ref, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			actualVal, err := client.Resolve(ctx, tc.ref)
+			if err != nil && !tc.wantErr {
+				t.Errorf("Resolve() returned an error: %v", err)
+			}
+			if err == nil && tc.wantErr {
+				t.Error("Resolve() did not return an error")
+			}
+			if actualVal != tc.wantVal {
+				t.Errorf("Resolve() == %#v, want %#v", actualVal, tc.wantVal)
+			}
+		})
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() returned an error: %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package sops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/busser/murmur/pkg/murmur/providers"
+	"github.com/getsops/sops/v3/cmd/sops/formats"
+	"github.com/getsops/sops/v3/decrypt"
+	"go.yaml.in/yaml/v3"
+)
+
+type client struct {
+	mu    sync.Mutex // protects files
+	files map[string]*decryptedFile
+}
+
+// decryptedFile holds the result of decrypting a file. The done channel is
+// closed once the other fields are set.
+type decryptedFile struct {
+	done      chan struct{}
+	format    formats.Format
+	cleartext []byte
+	err       error
+}
+
+// New returns a client that reads secrets from files encrypted with SOPS.
+//
+// Files are decrypted in-process. The client finds decryption keys the same
+// way the sops CLI does, for example with the age key file in
+// SOPS_AGE_KEY_FILE. Each file is decrypted only once, no matter how many
+// references point to it.
+func New() (*client, error) {
+	return &client{
+		files: make(map[string]*decryptedFile),
+	}, nil
+}
+
+func (c *client) Resolve(ctx context.Context, ref string) (string, error) {
+	path, keyPath, err := parseRef(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid reference: %w", err)
+	}
+
+	f, err := c.decryptFile(ctx, path)
+	if err != nil {
+		err = fmt.Errorf("failed to decrypt file %q: %w", path, err)
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", providers.NotFound(err)
+		}
+		return "", err
+	}
+
+	if len(keyPath) == 0 {
+		return string(f.cleartext), nil
+	}
+
+	doc, err := parseDocument(f.format, f.cleartext)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse decrypted file %q: %w", path, err)
+	}
+
+	value, err := lookup(doc, keyPath)
+	if err != nil {
+		return "", fmt.Errorf("file %q: %w", path, err)
+	}
+
+	return value, nil
+}
+
+func (c *client) Close() error {
+	// Decrypted files only live in memory for as long as the client does.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.files = nil
+
+	return nil
+}
+
+// decryptFile returns the decrypted contents of the file at path. Concurrent
+// calls for the same file share a single decryption.
+func (c *client) decryptFile(ctx context.Context, path string) (*decryptedFile, error) {
+	c.mu.Lock()
+	f, ok := c.files[path]
+	if !ok {
+		f = &decryptedFile{
+			done:   make(chan struct{}),
+			format: formats.FormatForPath(path),
+		}
+		c.files[path] = f
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		f.cleartext, f.err = decrypt.File(path, "")
+		close(f.done)
+	}
+
+	select {
+	case <-f.done:
+		return f, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// parseDocument parses the decrypted contents of a file, according to its
+// format, into a tree of maps, slices, and scalar values.
+func parseDocument(format formats.Format, cleartext []byte) (any, error) {
+	switch format {
+	case formats.Yaml, formats.Json:
+		// JSON is a subset of YAML, so a YAML parser handles both formats.
+		var doc any
+		if err := yaml.Unmarshal(cleartext, &doc); err != nil {
+			return nil, err
+		}
+		return doc, nil
+	case formats.Dotenv:
+		return parseDotenv(cleartext), nil
+	default:
+		return nil, errors.New("only YAML, JSON, and dotenv files support key paths")
+	}
+}
+
+// parseDotenv parses the dotenv files sops emits: one KEY=value pair per line,
+// with newlines in values escaped as "\n".
+func parseDotenv(cleartext []byte) map[string]any {
+	doc := make(map[string]any)
+
+	for _, line := range strings.Split(string(cleartext), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		doc[key] = strings.ReplaceAll(value, `\n`, "\n")
+	}
+
+	return doc
+}
+
+// lookup walks doc along keyPath and returns the value it finds. Scalar values
+// are returned as-is, while objects and lists are returned as JSON.
+func lookup(doc any, keyPath []string) (string, error) {
+	current := doc
+
+	for i, key := range keyPath {
+		switch node := current.(type) {
+		case map[string]any:
+			child, ok := node[key]
+			if !ok {
+				return "", providers.NotFound(fmt.Errorf("no key %q", strings.Join(keyPath[:i+1], ".")))
+			}
+			current = child
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return "", providers.NotFound(fmt.Errorf("no index %q", strings.Join(keyPath[:i+1], ".")))
+			}
+			current = node[index]
+		default:
+			return "", fmt.Errorf("%q is not an object or a list", strings.Join(keyPath[:i], "."))
+		}
+	}
+
+	switch value := current.(type) {
+	case string:
+		return value, nil
+	case map[string]any, []any:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode %q as JSON: %w", strings.Join(keyPath, "."), err)
+		}
+		return string(encoded), nil
+	case nil:
+		return "", nil
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
+
+func parseRef(ref string) (path string, keyPath []string, err error) {
+	path, rawKeyPath, _ := strings.Cut(ref, "#")
+	if path == "" {
+		return "", nil, errors.New("path cannot be empty")
+	}
+
+	if rawKeyPath == "" {
+		return path, nil, nil
+	}
+
+	keyPath = strings.Split(rawKeyPath, ".")
+	for _, key := range keyPath {
+		if key == "" {
+			return "", nil, fmt.Errorf("key path %q contains an empty key", rawKeyPath)
+		}
+	}
+
+	return path, keyPath, nil
+}
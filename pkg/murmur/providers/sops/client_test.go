@@ -0,0 +1,185 @@
+package sops_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/busser/murmur/pkg/murmur/providers"
+	"github.com/busser/murmur/pkg/murmur/providers/sops"
+)
+
+func Example() {
+	c, err := sops.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ref := "secrets.enc.yaml#database.password"
+	val, err := c.Resolve(context.Background(), ref)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("The database password is", val)
+}
+
+func TestClient(t *testing.T) {
+	// The files in testdata were encrypted with this age key.
+	t.Setenv("SOPS_AGE_KEY_FILE", "testdata/age-key.txt")
+
+	client, err := sops.New()
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+	defer client.Close()
+
+	tt := []struct {
+		ref          string
+		wantVal      string
+		wantErr      bool
+		wantNotFound bool
+	}{
+		// YAML files.
+		{
+			ref:     "testdata/secrets.enc.yaml#database.password",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "testdata/secrets.enc.yaml#database.port",
+			wantVal: "5432",
+		},
+		{
+			ref:     "testdata/secrets.enc.yaml#sauces.1",
+			wantVal: "mayonnaise",
+		},
+		{
+			ref:     "testdata/secrets.enc.yaml#sauces",
+			wantVal: `["ketchup","mayonnaise"]`,
+		},
+		{
+			ref:          "testdata/secrets.enc.yaml#database.origin",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			ref:          "testdata/secrets.enc.yaml#sauces.2",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			ref:     "testdata/secrets.enc.yaml#database.password.length",
+			wantErr: true,
+		},
+
+		// JSON files.
+		{
+			ref:     "testdata/secrets.enc.json#database.username",
+			wantVal: "chef",
+		},
+		{
+			ref:     "testdata/secrets.enc.json#database",
+			wantVal: `{"password":"szechuan","username":"chef"}`,
+		},
+
+		// Dotenv files.
+		{
+			ref:     "testdata/secrets.enc.env#DATABASE_PASSWORD",
+			wantVal: "szechuan",
+		},
+		{
+			ref:     "testdata/secrets.enc.env#MULTILINE",
+			wantVal: "first line\nsecond line",
+		},
+		{
+			ref:          "testdata/secrets.enc.env#OTHER_PASSWORD",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+
+		// Missing files and invalid references.
+		{
+			ref:          "testdata/missing.enc.yaml#database.password",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			ref:     "#database.password",
+			wantErr: true,
+		},
+		{
+			ref:     "testdata/secrets.enc.yaml#database..password",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.ref, func(t *testing.T) {
+			actualVal, err := client.Resolve(context.Background(), tc.ref)
+			if err != nil && !tc.wantErr {
+				t.Errorf("Resolve() returned an error: %v", err)
+			}
+			if err == nil && tc.wantErr {
+				t.Error("Resolve() did not return an error")
+			}
+			if errors.Is(err, providers.ErrNotFound) != tc.wantNotFound {
+				t.Errorf("errors.Is(err, ErrNotFound) == %t, want %t", !tc.wantNotFound, tc.wantNotFound)
+			}
+			if actualVal != tc.wantVal {
+				t.Errorf("Resolve() == %#v, want %#v", actualVal, tc.wantVal)
+			}
+		})
+	}
+}
+
+func TestClientWholeFile(t *testing.T) {
+	t.Setenv("SOPS_AGE_KEY_FILE", "testdata/age-key.txt")
+
+	client, err := sops.New()
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+	defer client.Close()
+
+	val, err := client.Resolve(context.Background(), "testdata/secrets.enc.env")
+	if err != nil {
+		t.Fatalf("Resolve() returned an error: %v", err)
+	}
+
+	if !strings.Contains(val, "DATABASE_PASSWORD=szechuan") {
+		t.Errorf("Resolve() == %#v, want the whole decrypted file", val)
+	}
+	if strings.Contains(val, "sops_") {
+		t.Errorf("Resolve() == %#v, should not contain sops metadata", val)
+	}
+}
+
+func TestClientWrongKey(t *testing.T) {
+	// A valid age key, but not the one the test files were encrypted with.
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "age-key.txt")
+	if err := os.WriteFile(keyFile, []byte(identity.String()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOPS_AGE_KEY_FILE", keyFile)
+
+	client, err := sops.New()
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+	defer client.Close()
+
+	_, err = client.Resolve(context.Background(), "testdata/secrets.enc.yaml#database.password")
+	if err == nil {
+		t.Fatal("Resolve() did not return an error")
+	}
+}
@@ -0,0 +1,415 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/busser/murmur/pkg/murmur/providers"
+)
+
+const (
+	defaultAppRoleMount      = "approle"
+	defaultKubernetesMount   = "kubernetes"
+	defaultKubernetesJWTPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+type client struct {
+	httpClient *http.Client
+	addr       string
+	namespace  string
+	token      string
+
+	mu     sync.Mutex // protects leases and kv2Mounts
+	leases []providers.Lease
+	// Whether each mount the client has seen, like "secret/", is a KV version
+	// 2 secrets engine.
+	kv2Mounts map[string]bool
+}
+
+// New returns a client that fetches secrets from HashiCorp Vault.
+//
+// The client connects to the Vault server at VAULT_ADDR. It authenticates with
+// the first method it finds configured in the environment:
+//   - VAULT_TOKEN: a Vault token;
+//   - VAULT_ROLE_ID and VAULT_SECRET_ID: AppRole authentication;
+//   - VAULT_K8S_ROLE: Kubernetes authentication, with the pod's service
+//     account token.
+//
+// The client skips verification of the server's TLS certificate if
+// VAULT_SKIP_VERIFY is true, like the Vault CLI does.
+func New(ctx context.Context) (*client, error) {
+	return NewWithOptions(ctx, Options{})
+}
+
+// Options configure a client.
+type Options struct {
+	// InsecureSkipVerify disables verification of the server's TLS certificate,
+	// for development servers with self-signed certificates. VAULT_SKIP_VERIFY
+	// does the same.
+	InsecureSkipVerify bool
+}
+
+// NewWithOptions works like New, but configures the client with the given
+// options.
+func NewWithOptions(ctx context.Context, opts Options) (*client, error) {
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return nil, errors.New("VAULT_ADDR is not set")
+	}
+
+	httpClient := &http.Client{}
+	if skip, _ := strconv.ParseBool(os.Getenv("VAULT_SKIP_VERIFY")); skip || opts.InsecureSkipVerify {
+		httpClient = providers.InsecureHTTPClient()
+	}
+
+	c := &client{
+		httpClient: httpClient,
+		addr:       strings.TrimSuffix(addr, "/"),
+		namespace:  os.Getenv("VAULT_NAMESPACE"),
+		kv2Mounts:  make(map[string]bool),
+	}
+
+	token, err := c.login(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate to Vault: %w", err)
+	}
+	c.token = token
+
+	return c, nil
+}
+
+func (c *client) Resolve(ctx context.Context, ref string) (string, error) {
+	path, version, err := parseRef(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid reference: %w", err)
+	}
+
+	query := url.Values{}
+	if version != "" {
+		query.Set("version", version)
+	}
+
+	var resp secretResponse
+	if err := c.do(ctx, http.MethodGet, path, query, nil, &resp); err != nil {
+		err = fmt.Errorf("failed to read secret %q version %q: %w", path, version, err)
+		var respErr *responseError
+		if errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound {
+			return "", providers.NotFound(err)
+		}
+		return "", err
+	}
+
+	kv2, err := c.isKV2(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %q version %q: %w", path, version, err)
+	}
+
+	// Dynamic secrets come with a lease, which the caller may want to renew or
+	// revoke later on.
+	if resp.LeaseID != "" {
+		c.mu.Lock()
+		c.leases = append(c.leases, &lease{
+			client:    c,
+			id:        resp.LeaseID,
+			ttl:       time.Duration(resp.LeaseDuration) * time.Second,
+			renewable: resp.Renewable,
+		})
+		c.mu.Unlock()
+	}
+
+	if kv2 {
+		return string(resp.kv2Data()), nil
+	}
+	return string(resp.Data), nil
+}
+
+// Leases returns the leases of all dynamic secrets the client has resolved.
+func (c *client) Leases() []providers.Lease {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	leases := make([]providers.Lease, len(c.leases))
+	copy(leases, c.leases)
+
+	return leases
+}
+
+func (c *client) Close() error {
+	// The client's leases may still need the HTTP client, so we only release
+	// idle connections.
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// isKV2 reports whether path is in a KV version 2 mount, whose responses nest
+// the secret's data. Like the Vault CLI, it asks Vault which secrets engine is
+// mounted at path. Results are cached by mount.
+func (c *client) isKV2(ctx context.Context, path string) (bool, error) {
+	c.mu.Lock()
+	for mount, kv2 := range c.kv2Mounts {
+		if strings.HasPrefix(path+"/", mount) {
+			c.mu.Unlock()
+			return kv2, nil
+		}
+	}
+	c.mu.Unlock()
+
+	var resp struct {
+		Data struct {
+			Path    string `json:"path"`
+			Type    string `json:"type"`
+			Options struct {
+				Version string `json:"version"`
+			} `json:"options"`
+		} `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, "sys/internal/ui/mounts/"+path, nil, nil, &resp); err != nil {
+		// Vault servers older than 1.1 do not have this endpoint, nor KV
+		// version 2 mounts.
+		var respErr *responseError
+		if errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to find mount: %w", err)
+	}
+
+	kv2 := resp.Data.Type == "kv" && resp.Data.Options.Version == "2"
+	if resp.Data.Path != "" {
+		c.mu.Lock()
+		c.kv2Mounts[resp.Data.Path] = kv2
+		c.mu.Unlock()
+	}
+
+	return kv2, nil
+}
+
+// login returns a Vault token obtained with the authentication method
+// configured in the environment.
+func (c *client) login(ctx context.Context) (string, error) {
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	if roleID := os.Getenv("VAULT_ROLE_ID"); roleID != "" {
+		mount := envOrDefault("VAULT_APPROLE_MOUNT", defaultAppRoleMount)
+		payload := map[string]string{
+			"role_id":   roleID,
+			"secret_id": os.Getenv("VAULT_SECRET_ID"),
+		}
+		return c.loginWith(ctx, mount, payload)
+	}
+
+	if role := os.Getenv("VAULT_K8S_ROLE"); role != "" {
+		mount := envOrDefault("VAULT_K8S_MOUNT", defaultKubernetesMount)
+		jwtPath := envOrDefault("VAULT_K8S_TOKEN_PATH", defaultKubernetesJWTPath)
+		jwt, err := os.ReadFile(jwtPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read service account token: %w", err)
+		}
+		payload := map[string]string{
+			"role": role,
+			"jwt":  strings.TrimSpace(string(jwt)),
+		}
+		return c.loginWith(ctx, mount, payload)
+	}
+
+	return "", errors.New("no credentials found, set VAULT_TOKEN, VAULT_ROLE_ID or VAULT_K8S_ROLE")
+}
+
+func (c *client) loginWith(ctx context.Context, mount string, payload map[string]string) (string, error) {
+	var resp struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+
+	path := "auth/" + strings.Trim(mount, "/") + "/login"
+	if err := c.do(ctx, http.MethodPost, path, nil, payload, &resp); err != nil {
+		return "", fmt.Errorf("login with %q: %w", path, err)
+	}
+
+	if resp.Auth.ClientToken == "" {
+		return "", fmt.Errorf("login with %q: no token in response", path)
+	}
+
+	return resp.Auth.ClientToken, nil
+}
+
+// do sends a request to the Vault HTTP API and decodes the response's body into
+// out.
+func (c *client) do(ctx context.Context, method, path string, query url.Values, payload, out any) error {
+	u := c.addr + "/v1/" + strings.TrimPrefix(path, "/")
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.token != "" {
+		req.Header.Set("X-Vault-Token", c.token)
+	}
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := newResponseError(resp)
+		if providers.TransientStatus(resp.StatusCode) {
+			return providers.Transient(err)
+		}
+		return err
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// secretResponse is the body Vault returns when reading a secret.
+type secretResponse struct {
+	Data          json.RawMessage `json:"data"`
+	LeaseID       string          `json:"lease_id"`
+	LeaseDuration int             `json:"lease_duration"`
+	Renewable     bool            `json:"renewable"`
+}
+
+// kv2Data returns the key-value pairs of a secret from a KV version 2 mount,
+// as a JSON object. These responses nest the secret's data and metadata inside
+// the response's data, so kv2Data unwraps them.
+func (r secretResponse) kv2Data() json.RawMessage {
+	var kv2 struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(r.Data, &kv2); err != nil || kv2.Data == nil {
+		return r.Data
+	}
+
+	return kv2.Data
+}
+
+// lease is the lease of a dynamic secret, like database credentials.
+type lease struct {
+	client    *client
+	id        string
+	ttl       time.Duration
+	renewable bool
+}
+
+func (l *lease) ID() string {
+	return l.id
+}
+
+func (l *lease) TTL() time.Duration {
+	return l.ttl
+}
+
+func (l *lease) Renewable() bool {
+	return l.renewable
+}
+
+func (l *lease) Renew(ctx context.Context) (time.Duration, error) {
+	var resp struct {
+		LeaseDuration int `json:"lease_duration"`
+	}
+
+	payload := map[string]any{"lease_id": l.id}
+	if err := l.client.do(ctx, http.MethodPut, "sys/leases/renew", nil, payload, &resp); err != nil {
+		return 0, fmt.Errorf("failed to renew lease %q: %w", l.id, err)
+	}
+
+	return time.Duration(resp.LeaseDuration) * time.Second, nil
+}
+
+func (l *lease) Revoke(ctx context.Context) error {
+	payload := map[string]any{"lease_id": l.id}
+	if err := l.client.do(ctx, http.MethodPut, "sys/leases/revoke", nil, payload, nil); err != nil {
+		return fmt.Errorf("failed to revoke lease %q: %w", l.id, err)
+	}
+
+	return nil
+}
+
+// responseError is returned when Vault responds with an error status code.
+type responseError struct {
+	StatusCode int
+	Errors     []string
+}
+
+func newResponseError(resp *http.Response) *responseError {
+	var body struct {
+		Errors []string `json:"errors"`
+	}
+	// The body may be empty or not JSON, in which case we only report the
+	// status code.
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+
+	return &responseError{
+		StatusCode: resp.StatusCode,
+		Errors:     body.Errors,
+	}
+}
+
+func (e *responseError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("vault responded with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("vault responded with status %d: %s", e.StatusCode, strings.Join(e.Errors, "; "))
+}
+
+func parseRef(ref string) (path, version string, err error) {
+	refParts := strings.SplitN(ref, "#", 2)
+	path = strings.Trim(refParts[0], "/")
+	if path == "" {
+		return "", "", errors.New("secret path cannot be empty")
+	}
+
+	if len(refParts) == 2 {
+		version = refParts[1]
+	}
+
+	return path, version, nil
+}
+
+func envOrDefault(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,456 @@
+package vault_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/busser/murmur/pkg/murmur/providers"
+	"github.com/busser/murmur/pkg/murmur/providers/vault"
+)
+
+func Example() {
+	c, err := vault.New(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ref := "secret/data/secret-sauce"
+	val, err := c.Resolve(context.Background(), ref)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("The secret sauce is", val)
+}
+
+const testToken = "s.test-token"
+
+// leaseLog records the lease operations a fake Vault server receives.
+type leaseLog struct {
+	mu      sync.Mutex
+	renewed []string
+	revoked []string
+}
+
+// newFakeVault returns a server that mimics a small subset of the Vault HTTP
+// API: a KV version 2 mount at "secret", a KV version 1 mount at "kv", a
+// database secrets engine at "database", lease management endpoints, and the
+// AppRole and Kubernetes login endpoints.
+func newFakeVault(t *testing.T) *httptest.Server {
+	srv, _ := newFakeVaultWithLeases(t)
+	return srv
+}
+
+func newFakeVaultWithLeases(t *testing.T) (*httptest.Server, *leaseLog) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+
+	reply := func(w http.ResponseWriter, status int, body any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	}
+
+	authenticated := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Vault-Token") != testToken {
+				reply(w, http.StatusForbidden, map[string]any{"errors": []string{"permission denied"}})
+				return
+			}
+			next(w, r)
+		}
+	}
+
+	login := func(field, want string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var payload map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload[field] != want {
+				reply(w, http.StatusBadRequest, map[string]any{"errors": []string{"invalid credentials"}})
+				return
+			}
+			reply(w, http.StatusOK, map[string]any{"auth": map[string]any{"client_token": testToken}})
+		}
+	}
+
+	mux.HandleFunc("POST /v1/auth/approle/login", login("secret_id", "my-secret-id"))
+	mux.HandleFunc("POST /v1/auth/kubernetes/login", login("jwt", "my-jwt"))
+
+	mux.HandleFunc("GET /v1/secret/data/secret-sauce", authenticated(func(w http.ResponseWriter, r *http.Request) {
+		sauce := map[string]string{"1": "ketchup", "2": "szechuan", "": "szechuan"}
+		value, ok := sauce[r.URL.Query().Get("version")]
+		if !ok {
+			reply(w, http.StatusNotFound, map[string]any{"errors": []string{}})
+			return
+		}
+		reply(w, http.StatusOK, map[string]any{
+			"data": map[string]any{
+				"data":     map[string]string{"sauce": value},
+				"metadata": map[string]any{"version": 2},
+			},
+		})
+	}))
+
+	mux.HandleFunc("GET /v1/kv/secret-sauce", authenticated(func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusOK, map[string]any{
+			"data": map[string]string{"sauce": "szechuan"},
+		})
+	}))
+
+	// A KV version 1 secret that looks like a KV version 2 response.
+	mux.HandleFunc("GET /v1/kv/lookalike", authenticated(func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusOK, map[string]any{
+			"data": map[string]any{
+				"data":     map[string]string{"sauce": "ketchup"},
+				"metadata": map[string]any{"owner": "chef"},
+			},
+		})
+	}))
+
+	mounts := map[string]map[string]any{
+		"secret/":   {"path": "secret/", "type": "kv", "options": map[string]string{"version": "2"}},
+		"kv/":       {"path": "kv/", "type": "kv", "options": map[string]string{"version": "1"}},
+		"database/": {"path": "database/", "type": "database", "options": nil},
+	}
+	mux.HandleFunc("GET /v1/sys/internal/ui/mounts/{path...}", authenticated(func(w http.ResponseWriter, r *http.Request) {
+		for prefix, mount := range mounts {
+			if strings.HasPrefix(r.PathValue("path")+"/", prefix) {
+				reply(w, http.StatusOK, map[string]any{"data": mount})
+				return
+			}
+		}
+		reply(w, http.StatusBadRequest, map[string]any{"errors": []string{"no secret engine mount found"}})
+	}))
+
+	mux.HandleFunc("GET /v1/database/creds/readonly", authenticated(func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusOK, map[string]any{
+			"lease_id":       "database/creds/readonly/abc123",
+			"lease_duration": 3600,
+			"renewable":      true,
+			"data": map[string]string{
+				"username": "v-token-readonly-xyz",
+				"password": "A1a-random",
+			},
+		})
+	}))
+
+	var leases leaseLog
+
+	leaseOperation := func(record *[]string) http.HandlerFunc {
+		return authenticated(func(w http.ResponseWriter, r *http.Request) {
+			var payload map[string]any
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				reply(w, http.StatusBadRequest, map[string]any{"errors": []string{"invalid request"}})
+				return
+			}
+			leaseID, _ := payload["lease_id"].(string)
+
+			leases.mu.Lock()
+			*record = append(*record, leaseID)
+			leases.mu.Unlock()
+
+			reply(w, http.StatusOK, map[string]any{
+				"lease_id":       leaseID,
+				"lease_duration": 1800,
+				"renewable":      true,
+			})
+		})
+	}
+
+	mux.HandleFunc("PUT /v1/sys/leases/renew", leaseOperation(&leases.renewed))
+	mux.HandleFunc("PUT /v1/sys/leases/revoke", leaseOperation(&leases.revoked))
+
+	mux.HandleFunc("/", authenticated(func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusNotFound, map[string]any{"errors": []string{}})
+	}))
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv, &leases
+}
+
+func TestClient(t *testing.T) {
+	srv := newFakeVault(t)
+
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", testToken)
+
+	client, err := vault.New(context.Background())
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+	defer client.Close()
+
+	tt := []struct {
+		ref          string
+		wantVal      string
+		wantErr      bool
+		wantNotFound bool
+	}{
+		// KV version 2.
+		{
+			ref:     "secret/data/secret-sauce",
+			wantVal: `{"sauce":"szechuan"}`,
+		},
+		{
+			ref:     "secret/data/secret-sauce#2",
+			wantVal: `{"sauce":"szechuan"}`,
+		},
+		{
+			ref:     "secret/data/secret-sauce#1",
+			wantVal: `{"sauce":"ketchup"}`,
+		},
+		{
+			ref:          "secret/data/secret-sauce#3",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			ref:          "secret/data/does-not-exist",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+
+		// KV version 1.
+		{
+			ref:     "kv/secret-sauce",
+			wantVal: `{"sauce":"szechuan"}`,
+		},
+		{
+			ref:     "kv/lookalike",
+			wantVal: `{"data":{"sauce":"ketchup"},"metadata":{"owner":"chef"}}`,
+		},
+		{
+			ref:          "kv/does-not-exist",
+			wantErr:      true,
+			wantNotFound: true,
+		},
+
+		// Invalid references.
+		{
+			ref:     "#1",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.ref, func(t *testing.T) {
+			actualVal, err := client.Resolve(context.Background(), tc.ref)
+			if err != nil && !tc.wantErr {
+				t.Errorf("Resolve() returned an error: %v", err)
+			}
+			if err == nil && tc.wantErr {
+				t.Error("Resolve() did not return an error")
+			}
+			if errors.Is(err, providers.ErrNotFound) != tc.wantNotFound {
+				t.Errorf("errors.Is(err, ErrNotFound) == %t, want %t", !tc.wantNotFound, tc.wantNotFound)
+			}
+			if strings.TrimSpace(actualVal) != tc.wantVal {
+				t.Errorf("Resolve() == %#v, want %#v", actualVal, tc.wantVal)
+			}
+		})
+	}
+}
+
+func TestClientAuthentication(t *testing.T) {
+	srv := newFakeVault(t)
+
+	jwtPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(jwtPath, []byte("my-jwt\n"), 0o600); err != nil {
+		t.Fatalf("could not write service account token: %v", err)
+	}
+
+	tt := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{
+			name: "token",
+			env:  map[string]string{"VAULT_TOKEN": testToken},
+		},
+		{
+			name:    "wrong token",
+			env:     map[string]string{"VAULT_TOKEN": "s.wrong"},
+			wantErr: true,
+		},
+		{
+			name: "approle",
+			env: map[string]string{
+				"VAULT_ROLE_ID":   "my-role",
+				"VAULT_SECRET_ID": "my-secret-id",
+			},
+		},
+		{
+			name: "approle with wrong secret ID",
+			env: map[string]string{
+				"VAULT_ROLE_ID":   "my-role",
+				"VAULT_SECRET_ID": "wrong",
+			},
+			wantErr: true,
+		},
+		{
+			name: "kubernetes",
+			env: map[string]string{
+				"VAULT_K8S_ROLE":       "my-role",
+				"VAULT_K8S_TOKEN_PATH": jwtPath,
+			},
+		},
+		{
+			name: "kubernetes without service account token",
+			env: map[string]string{
+				"VAULT_K8S_ROLE":       "my-role",
+				"VAULT_K8S_TOKEN_PATH": filepath.Join(t.TempDir(), "missing"),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "no credentials",
+			env:     nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, key := range []string{"VAULT_TOKEN", "VAULT_ROLE_ID", "VAULT_SECRET_ID", "VAULT_K8S_ROLE", "VAULT_K8S_TOKEN_PATH"} {
+				t.Setenv(key, "")
+			}
+			t.Setenv("VAULT_ADDR", srv.URL)
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			// Authentication errors surface either when creating the client or
+			// when the client first uses its token.
+			client, err := vault.New(context.Background())
+			if err == nil {
+				defer client.Close()
+				_, err = client.Resolve(context.Background(), "kv/secret-sauce")
+			}
+
+			if err != nil && !tc.wantErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if err == nil && tc.wantErr {
+				t.Error("expected an error, got none")
+			}
+		})
+	}
+}
+
+func TestClientDynamicSecrets(t *testing.T) {
+	srv, leaseLog := newFakeVaultWithLeases(t)
+
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", testToken)
+
+	client, err := vault.New(context.Background())
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+
+	val, err := client.Resolve(context.Background(), "database/creds/readonly")
+	if err != nil {
+		t.Fatalf("Resolve() returned an error: %v", err)
+	}
+	wantVal := `{"password":"A1a-random","username":"v-token-readonly-xyz"}`
+	if strings.TrimSpace(val) != wantVal {
+		t.Errorf("Resolve() == %#v, want %#v", val, wantVal)
+	}
+
+	leases := client.Leases()
+	if len(leases) != 1 {
+		t.Fatalf("Leases() returned %d leases, want 1", len(leases))
+	}
+	lease := leases[0]
+
+	if lease.ID() != "database/creds/readonly/abc123" {
+		t.Errorf("lease.ID() == %q, want %q", lease.ID(), "database/creds/readonly/abc123")
+	}
+	if lease.TTL() != time.Hour {
+		t.Errorf("lease.TTL() == %v, want %v", lease.TTL(), time.Hour)
+	}
+	if !lease.Renewable() {
+		t.Error("lease.Renewable() == false, want true")
+	}
+
+	// Leases must remain usable after the client is closed.
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() returned an error: %v", err)
+	}
+
+	ttl, err := lease.Renew(context.Background())
+	if err != nil {
+		t.Fatalf("lease.Renew() returned an error: %v", err)
+	}
+	if ttl != 30*time.Minute {
+		t.Errorf("lease.Renew() == %v, want %v", ttl, 30*time.Minute)
+	}
+
+	if err := lease.Revoke(context.Background()); err != nil {
+		t.Fatalf("lease.Revoke() returned an error: %v", err)
+	}
+
+	leaseLog.mu.Lock()
+	defer leaseLog.mu.Unlock()
+	if len(leaseLog.renewed) != 1 || leaseLog.renewed[0] != lease.ID() {
+		t.Errorf("server renewed leases %q, want %q", leaseLog.renewed, lease.ID())
+	}
+	if len(leaseLog.revoked) != 1 || leaseLog.revoked[0] != lease.ID() {
+		t.Errorf("server revoked leases %q, want %q", leaseLog.revoked, lease.ID())
+	}
+}
+
+func TestClientSkipVerify(t *testing.T) {
+	srv := httptest.NewTLSServer(newFakeVault(t).Config.Handler)
+	t.Cleanup(srv.Close)
+
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", testToken)
+
+	// The server's certificate is self-signed.
+	client, err := vault.New(context.Background())
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.Resolve(context.Background(), "secret/data/secret-sauce"); err == nil {
+		t.Error("Resolve() did not return an error")
+	}
+
+	client, err = vault.NewWithOptions(context.Background(), vault.Options{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("NewWithOptions() returned an error: %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.Resolve(context.Background(), "secret/data/secret-sauce"); err != nil {
+		t.Errorf("Resolve() returned an error with InsecureSkipVerify: %v", err)
+	}
+
+	t.Setenv("VAULT_SKIP_VERIFY", "true")
+
+	client, err = vault.New(context.Background())
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.Resolve(context.Background(), "secret/data/secret-sauce"); err != nil {
+		t.Errorf("Resolve() returned an error with VAULT_SKIP_VERIFY: %v", err)
+	}
+}
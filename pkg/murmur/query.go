@@ -10,11 +10,30 @@ type query struct {
 	providerID string
 	secretRef  string
 
+	// Whether the query falls back to defaultValue when the secret does not
+	// exist, instead of failing.
+	optional     bool
+	defaultValue string
+
+	// Filters to apply to the secret's value, in order.
+	filters []filterStep
+}
+
+type filterStep struct {
 	filterID   string
 	filterRule string
 }
 
-func parseQuery(s string) (query, error) {
+// parseQuery parses queries like "provider:ref|filter:rule|filter:rule".
+//
+// The ref may end with "?optional" or "?default=value", in which case the query
+// falls back to an empty string or to the given value when the secret does not
+// exist.
+//
+// The first "|" always separates the secret from the first filter. Since
+// filter rules may contain "|", later occurrences only start a new filter step
+// when followed by the ID of one of the given filters and a ":".
+func parseQuery(s string, filters map[string]Filter) (query, error) {
 	if len(s) == 0 {
 		return query{}, errors.New("empty query")
 	}
@@ -28,24 +47,52 @@ func parseQuery(s string) (query, error) {
 		return query{}, fmt.Errorf("left of first %q: %w", separator, err)
 	}
 
+	q := query{
+		providerID: providerID,
+	}
+	q.secretRef, q.optional, q.defaultValue = parseQueryFallback(secretRef)
+	if q.secretRef == "" {
+		return query{}, fmt.Errorf("left of first %q: reference cannot be empty string", separator)
+	}
+
 	if len(parts) == 1 {
-		return query{
-			providerID: providerID,
-			secretRef:  secretRef,
-		}, nil
+		return q, nil
 	}
 
-	filterID, filterRule, err := parseQueryFilter(parts[1])
-	if err != nil {
-		return query{}, fmt.Errorf("right of first %q: %w", separator, err)
+	for i, rawStep := range splitFilterSteps(parts[1], separator, filters) {
+		filterID, filterRule, err := parseQueryFilter(rawStep)
+		if err != nil {
+			return query{}, fmt.Errorf("filter step %d: %w", i+1, err)
+		}
+		q.filters = append(q.filters, filterStep{
+			filterID:   filterID,
+			filterRule: filterRule,
+		})
 	}
 
-	return query{
-		providerID: providerID,
-		secretRef:  secretRef,
-		filterID:   filterID,
-		filterRule: filterRule,
-	}, nil
+	return q, nil
+}
+
+// splitFilterSteps splits s on each occurrence of separator followed by the ID
+// of one of the given filters and a ":".
+func splitFilterSteps(s, separator string, filters map[string]Filter) []string {
+	var steps []string
+
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if !strings.HasPrefix(s[i:], separator) {
+			continue
+		}
+		next := s[i+len(separator):]
+		filterID, _, found := strings.Cut(next, ":")
+		if _, known := filters[filterID]; !found || !known {
+			continue
+		}
+		steps = append(steps, s[start:i])
+		start = i + len(separator)
+	}
+
+	return append(steps, s[start:])
 }
 
 func parseQuerySecret(s string) (providerID, secretRef string, err error) {
@@ -67,6 +114,25 @@ func parseQuerySecret(s string) (providerID, secretRef string, err error) {
 	return parts[0], parts[1], nil
 }
 
+// parseQueryFallback extracts the fallback value from a reference, if it has
+// one.
+func parseQueryFallback(s string) (secretRef string, optional bool, defaultValue string) {
+	const (
+		optionalSuffix = "?optional"
+		defaultMarker  = "?default="
+	)
+
+	if ref, found := strings.CutSuffix(s, optionalSuffix); found {
+		return ref, true, ""
+	}
+
+	if ref, value, found := strings.Cut(s, defaultMarker); found {
+		return ref, true, value
+	}
+
+	return s, false, ""
+}
+
 func parseQueryFilter(s string) (filterID, filterRule string, err error) {
 	const separator = ":"
 
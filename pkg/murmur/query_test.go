@@ -30,8 +30,9 @@ func TestParseQuery(t *testing.T) {
 			want: query{
 				providerID: "my_provider",
 				secretRef:  "my_secret:my_version",
-				filterID:   "my_filter",
-				filterRule: "my_filter_rule",
+				filters: []filterStep{
+					{filterID: "my_filter", filterRule: "my_filter_rule"},
+				},
 			},
 		},
 		{
@@ -39,10 +40,98 @@ func TestParseQuery(t *testing.T) {
 			want: query{
 				providerID: "my_provider",
 				secretRef:  "my_secret:my_version",
-				filterID:   "my_filter",
-				filterRule: "my:complex|filter:rule",
+				filters: []filterStep{
+					{filterID: "my_filter", filterRule: "my:complex|filter:rule"},
+				},
 			},
 		},
+		{
+			s: "my_provider:my_secret|base64:decode|jsonpath:{.password}",
+			want: query{
+				providerID: "my_provider",
+				secretRef:  "my_secret",
+				filters: []filterStep{
+					{filterID: "base64", filterRule: "decode"},
+					{filterID: "jsonpath", filterRule: "{.password}"},
+				},
+			},
+		},
+		{
+			s: "my_provider:my_secret|jsonpath:{.a}|{.b}|base64:encode",
+			want: query{
+				providerID: "my_provider",
+				secretRef:  "my_secret",
+				filters: []filterStep{
+					{filterID: "jsonpath", filterRule: "{.a}|{.b}"},
+					{filterID: "base64", filterRule: "encode"},
+				},
+			},
+		},
+		{
+			s: "my_provider:my_secret|jsonpath:{.a}|x:y",
+			want: query{
+				providerID: "my_provider",
+				secretRef:  "my_secret",
+				filters: []filterStep{
+					{filterID: "jsonpath", filterRule: "{.a}|x:y"},
+				},
+			},
+		},
+		{
+			s: "my_provider:my_secret|my_filter:my_rule|base64:decode",
+			want: query{
+				providerID: "my_provider",
+				secretRef:  "my_secret",
+				filters: []filterStep{
+					{filterID: "my_filter", filterRule: "my_rule"},
+					{filterID: "base64", filterRule: "decode"},
+				},
+			},
+		},
+		{
+			s: "my_provider:my_secret?optional",
+			want: query{
+				providerID: "my_provider",
+				secretRef:  "my_secret",
+				optional:   true,
+			},
+		},
+		{
+			s: "my_provider:my_secret?default=off|jsonpath:{.flag}",
+			want: query{
+				providerID:   "my_provider",
+				secretRef:    "my_secret",
+				optional:     true,
+				defaultValue: "off",
+				filters: []filterStep{
+					{filterID: "jsonpath", filterRule: "{.flag}"},
+				},
+			},
+		},
+		{
+			s: "my_provider:my_secret?default=a?default=b",
+			want: query{
+				providerID:   "my_provider",
+				secretRef:    "my_secret",
+				optional:     true,
+				defaultValue: "a?default=b",
+			},
+		},
+		{
+			s: "my_provider:my_secret?version=2",
+			want: query{
+				providerID: "my_provider",
+				secretRef:  "my_secret?version=2",
+			},
+		},
+		{
+			s:       "my_provider:?optional",
+			wantErr: true,
+		},
+		{
+			s:       "my_provider:my_secret|base64:decode|base64:",
+			wantErr: true,
+		},
 		{
 			s:       "",
 			wantErr: true,
@@ -87,7 +176,7 @@ func TestParseQuery(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.s, func(t *testing.T) {
-			actual, err := parseQuery(tc.s)
+			actual, err := parseQuery(tc.s, Filters)
 
 			if err != nil && !tc.wantErr {
 				t.Errorf("unexpected error: %v", err)
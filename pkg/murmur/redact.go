@@ -0,0 +1,248 @@
+package murmur
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// redactionMask replaces secrets in redacted output.
+const redactionMask = "***"
+
+// minRedactedLength is the length of the shortest secrets murmur redacts.
+// Shorter values, like "1" or "true", are too likely to appear in output by
+// chance, and redacting them would garble it.
+const minRedactedLength = 4
+
+// redactionPatterns returns the byte sequences to redact from output for the
+// given secrets: the secrets themselves, each line of multi-line secrets, and
+// their common encodings. Patterns are sorted longest first, so that the
+// longest of several patterns starting at the same position is redacted.
+func redactionPatterns(secrets []string) [][]byte {
+	var values []string
+	for _, secret := range secrets {
+		values = append(values, secret)
+		// Applications often print multi-line secrets, like private keys, one
+		// line at a time.
+		if strings.Contains(secret, "\n") {
+			for line := range strings.Lines(secret) {
+				values = append(values, strings.TrimSpace(line))
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	var patterns [][]byte
+	add := func(p string) {
+		if len(p) < minRedactedLength || seen[p] {
+			return
+		}
+		seen[p] = true
+		patterns = append(patterns, []byte(p))
+	}
+
+	for _, v := range values {
+		if len(v) < minRedactedLength {
+			continue
+		}
+
+		add(v)
+
+		b := []byte(v)
+		add(base64.StdEncoding.EncodeToString(b))
+		add(base64.RawStdEncoding.EncodeToString(b))
+		add(base64.URLEncoding.EncodeToString(b))
+		add(base64.RawURLEncoding.EncodeToString(b))
+
+		add(url.QueryEscape(v))
+		add(url.PathEscape(v))
+
+		add(jsonEscape(v, true))
+		add(jsonEscape(v, false))
+	}
+
+	slices.SortStableFunc(patterns, func(a, b []byte) int {
+		return len(b) - len(a)
+	})
+
+	return patterns
+}
+
+// jsonEscape returns s as it appears inside a JSON string, with or without
+// escaping HTML characters like Go's encoding/json does by default.
+func jsonEscape(s string, escapeHTML bool) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(escapeHTML)
+	_ = enc.Encode(s) // Strings always encode.
+
+	quoted := strings.TrimSuffix(buf.String(), "\n")
+	return quoted[1 : len(quoted)-1]
+}
+
+// A redactor writes everything written to it to another writer, with each
+// occurrence of its patterns replaced with redactionMask. Overlapping
+// occurrences, like those of a secret that contains another, are replaced
+// together, so that no part of either ends up in the output.
+//
+// A pattern may be split across several writes, so the redactor holds back the
+// end of a write when it is the beginning of a pattern, until the next write or
+// Close tells whether the pattern is complete. Close must be called to flush
+// what the redactor holds back.
+type redactor struct {
+	w        io.Writer
+	patterns [][]byte // longest first
+	// Bytes written to the redactor, but not yet to w. They are shorter than
+	// the longest pattern.
+	pending []byte
+	// How many bytes at the start of pending are part of an occurrence whose
+	// mask was already written to w.
+	masked int
+}
+
+// newRedactor returns a redactor that writes to w, redacting patterns, which
+// must be sorted longest first.
+func newRedactor(w io.Writer, patterns [][]byte) *redactor {
+	return &redactor{
+		w:        w,
+		patterns: patterns,
+	}
+}
+
+func (r *redactor) Write(p []byte) (int, error) {
+	if len(r.patterns) == 0 {
+		return r.w.Write(p)
+	}
+
+	out, rest := r.redact(append(r.pending, p...), false)
+	r.pending = slices.Clone(rest)
+
+	if _, err := r.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// redact returns buf with each occurrence of a pattern replaced with
+// redactionMask. Unless final is set, it returns the end of buf, where a
+// pattern may start and end in the next write, separately, as rest.
+func (r *redactor) redact(buf []byte, final bool) (out, rest []byte) {
+	// Bytes before cut cannot be part of an occurrence that ends in a later
+	// write, so they are ready to go.
+	cut := len(buf)
+	if !final {
+		cut = r.partialMatchStart(buf)
+	}
+
+	pos := 0
+	// If nothing is ready to go, the beginning of buf stays masked.
+	masked := max(0, r.masked-cut)
+	for _, span := range r.occurrences(buf) {
+		if span.start >= cut {
+			break
+		}
+		out = append(out, buf[pos:span.start]...)
+		// The beginning of pending may continue an occurrence that is
+		// already masked.
+		if span.start > 0 || r.masked == 0 {
+			out = append(out, redactionMask...)
+		}
+		pos = span.end
+		// An occurrence that goes past cut may overlap with another that
+		// ends in a later write. Its mask is written now, but its end is held
+		// back, so that both are masked together.
+		if span.end > cut {
+			masked = span.end - cut
+			pos = cut
+			break
+		}
+	}
+	out = append(out, buf[pos:cut]...)
+	r.masked = masked
+
+	return out, buf[cut:]
+}
+
+// A span is a range of bytes, from start included to end excluded.
+type span struct {
+	start, end int
+}
+
+// occurrences returns the ranges of b that occurrences of patterns cover,
+// merged when they overlap, in order. The already masked beginning of pending
+// counts as an occurrence.
+func (r *redactor) occurrences(b []byte) []span {
+	var spans []span
+	if r.masked > 0 {
+		spans = append(spans, span{0, min(r.masked, len(b))})
+	}
+	for _, pattern := range r.patterns {
+		for i := 0; i < len(b); {
+			j := bytes.Index(b[i:], pattern)
+			if j < 0 {
+				break
+			}
+			spans = append(spans, span{i + j, i + j + len(pattern)})
+			i += j + 1
+		}
+	}
+
+	slices.SortFunc(spans, func(a, b span) int {
+		return a.start - b.start
+	})
+
+	var merged []span
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s.start < merged[n-1].end {
+			merged[n-1].end = max(merged[n-1].end, s.end)
+			continue
+		}
+		merged = append(merged, s)
+	}
+
+	return merged
+}
+
+// partialMatchStart returns the position of the earliest suffix of b that is
+// the beginning of a longer pattern, or len(b) if there is none. Holding back
+// as little as possible means output without secrets, like a prompt, is not
+// delayed.
+func (r *redactor) partialMatchStart(b []byte) int {
+	for i := max(0, len(b)-len(r.patterns[0])+1); i < len(b); i++ {
+		if r.isPartialMatch(b[i:]) {
+			return i
+		}
+	}
+	return len(b)
+}
+
+// isPartialMatch reports whether b is the beginning of a pattern longer than
+// b itself.
+func (r *redactor) isPartialMatch(b []byte) bool {
+	for _, pattern := range r.patterns {
+		if len(pattern) <= len(b) {
+			// Patterns are sorted longest first.
+			return false
+		}
+		if bytes.HasPrefix(pattern, b) {
+			return true
+		}
+	}
+	return false
+}
+
+// Close redacts and writes the bytes the redactor holds back.
+func (r *redactor) Close() error {
+	if len(r.pending) == 0 {
+		return nil
+	}
+	out, _ := r.redact(r.pending, true)
+	r.pending = nil
+	r.masked = 0
+	_, err := r.w.Write(out)
+	return err
+}
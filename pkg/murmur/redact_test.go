@@ -0,0 +1,227 @@
+package murmur
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestRedactionPatterns(t *testing.T) {
+	patterns := redactionPatterns([]string{
+		"sweet & sour",
+		"abc", // Too short.
+		"-----BEGIN KEY-----\nc2VjcmV0\n-----END KEY-----\n",
+	})
+
+	var got []string
+	for _, p := range patterns {
+		got = append(got, string(p))
+	}
+
+	want := []string{
+		"sweet & sour",
+		"c3dlZXQgJiBzb3Vy", // base64, without padding either way
+		"sweet+%26+sour",   // query escaping
+		"sweet%20&%20sour", // path escaping
+		`sweet & sour`,
+		"-----BEGIN KEY-----",
+		"c2VjcmV0",
+		"-----END KEY-----",
+		`-----BEGIN KEY-----\nc2VjcmV0\n-----END KEY-----\n`,
+	}
+	for _, w := range want {
+		if !slices.Contains(got, w) {
+			t.Errorf("redactionPatterns() does not contain %q", w)
+		}
+	}
+	for _, g := range got {
+		if g == "abc" {
+			t.Errorf("redactionPatterns() contains %q, which is too short", g)
+		}
+	}
+
+	for i := 1; i < len(patterns); i++ {
+		if len(patterns[i]) > len(patterns[i-1]) {
+			t.Errorf("redactionPatterns() is not sorted longest first: %q before %q", patterns[i-1], patterns[i])
+		}
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	patterns := redactionPatterns([]string{"szechuan", "hunter2", "sweet & sour"})
+
+	tt := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "no secrets",
+			input: "nothing to see here\n",
+			want:  "nothing to see here\n",
+		},
+		{
+			name:  "one secret",
+			input: "password=hunter2\n",
+			want:  "password=***\n",
+		},
+		{
+			name:  "several secrets",
+			input: "szechuan hunter2 szechuanszechuan\n",
+			want:  "*** *** ******\n",
+		},
+		{
+			name:  "base64",
+			input: "c3plY2h1YW4= c3plY2h1YW4\n",
+			want:  "*** ***\n",
+		},
+		{
+			name:  "URL encoding",
+			input: "https://example.com/?sauce=sweet+%26+sour&path=sweet%20&%20sour\n",
+			want:  "https://example.com/?sauce=***&path=***\n",
+		},
+		{
+			name:  "JSON",
+			input: `{"sauce":"sweet & sour"}`,
+			want:  `{"sauce":"***"}`,
+		},
+		{
+			name:  "secret at the end",
+			input: "the password is hunter2",
+			want:  "the password is ***",
+		},
+		{
+			name:  "partial secret at the end",
+			input: "the password is hunter",
+			want:  "the password is hunter",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Secrets may be split across writes in any way.
+			for chunkSize := 1; chunkSize <= len(tc.input); chunkSize++ {
+				var out bytes.Buffer
+				r := newRedactor(&out, patterns)
+
+				for chunk := range slices.Chunk([]byte(tc.input), chunkSize) {
+					n, err := r.Write(chunk)
+					if err != nil {
+						t.Fatalf("Write() returned an error: %v", err)
+					}
+					if n != len(chunk) {
+						t.Fatalf("Write() = %d, want %d", n, len(chunk))
+					}
+				}
+				if err := r.Close(); err != nil {
+					t.Fatalf("Close() returned an error: %v", err)
+				}
+
+				if out.String() != tc.want {
+					t.Errorf("with writes of %d bytes, got %q, want %q", chunkSize, out.String(), tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRedactorOverlappingSecrets(t *testing.T) {
+	// Secrets may contain each other, or overlap.
+	patterns := redactionPatterns([]string{"abcdefgh", "cdef", "ghijkl", "defg"})
+
+	tt := []struct {
+		input string
+		want  string
+	}{
+		{
+			input: "1 abcdefgh 2",
+			want:  "1 *** 2",
+		},
+		{
+			input: "1 cdef 2",
+			want:  "1 *** 2",
+		},
+		{
+			input: "1 abcdefghijkl 2",
+			want:  "1 *** 2",
+		},
+		{
+			input: "1 xcdefghijkl 2",
+			want:  "1 x*** 2",
+		},
+		{
+			input: "1 abcdefg 2",
+			want:  "1 ab*** 2",
+		},
+		{
+			input: "abcdefgh",
+			want:  "***",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.input, func(t *testing.T) {
+			// Split the input in two writes, at every possible offset.
+			for i := 0; i <= len(tc.input); i++ {
+				var out bytes.Buffer
+				r := newRedactor(&out, patterns)
+
+				r.Write([]byte(tc.input[:i]))
+				r.Write([]byte(tc.input[i:]))
+				if err := r.Close(); err != nil {
+					t.Fatalf("Close() returned an error: %v", err)
+				}
+
+				if out.String() != tc.want {
+					t.Errorf("with writes of %q and %q, got %q, want %q", tc.input[:i], tc.input[i:], out.String(), tc.want)
+				}
+			}
+
+			// And one byte at a time.
+			var out bytes.Buffer
+			r := newRedactor(&out, patterns)
+			for i := range len(tc.input) {
+				r.Write([]byte{tc.input[i]})
+			}
+			r.Close()
+			if out.String() != tc.want {
+				t.Errorf("with writes of 1 byte, got %q, want %q", out.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestRedactorHoldsBackPartialMatches(t *testing.T) {
+	var out bytes.Buffer
+	r := newRedactor(&out, redactionPatterns([]string{"hunter2"}))
+
+	// Output that cannot be the beginning of a secret is written right away,
+	// so that prompts show up.
+	r.Write([]byte("Password: "))
+	if out.String() != "Password: " {
+		t.Errorf("got %q, want %q", out.String(), "Password: ")
+	}
+
+	r.Write([]byte("OK\nhunt"))
+	if out.String() != "Password: OK\n" {
+		t.Errorf("got %q, want %q", out.String(), "Password: OK\n")
+	}
+
+	r.Write([]byte("er2\n"))
+	if out.String() != "Password: OK\n***\n" {
+		t.Errorf("got %q, want %q", out.String(), "Password: OK\n***\n")
+	}
+}
+
+func TestRedactorWithoutPatterns(t *testing.T) {
+	var out bytes.Buffer
+	r := newRedactor(&out, nil)
+
+	r.Write([]byte("hello, "))
+	r.Write([]byte("world\n"))
+
+	// Nothing is held back.
+	if out.String() != "hello, world\n" {
+		t.Errorf("got %q, want %q", out.String(), "hello, world\n")
+	}
+}
@@ -2,10 +2,13 @@ package murmur
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
 	"sync"
-
-	"github.com/hashicorp/go-multierror"
+	"time"
 )
 
 type variable struct {
@@ -17,18 +20,31 @@ type variable struct {
 	query *query
 	// The resolved value of the secret referenced in the query.
 	resolvedValue string
+	// Whether the secret does not exist and resolvedValue is the query's
+	// fallback value.
+	fallback bool
 	// The filtered value of the secret.
 	filteredValue string
 	// The final value of the environment variable.
 	finalValue string
-	// Any error that occurred while processing the environment variable.
-	err error
+	// Any error that occurred while processing the environment variable, and
+	// the phase of resolution during which it occurred.
+	err   error
+	phase Phase
+
+	// If the variable's value embeds several queries, each query goes through
+	// the pipeline separately. All of them share the same interpolation, and
+	// fragment is the index of the query within it.
+	interpolation *interpolation
+	fragment      int
 }
 
 // ResolveAll resolves all secret references in the input map and returns the resolved values.
 // 
-// Input map keys are preserved. Values that contain valid murmur queries (format: "provider:ref|filter:rule")
-// are resolved by fetching from the appropriate secret store. Values without valid queries pass through unchanged.
+// Input map keys are preserved. Values that contain valid murmur queries (format: "provider:ref|filter:rule|...")
+// are resolved by fetching from the appropriate secret store. Values may also embed several queries in a literal
+// string with the ${query} syntax, like "postgres://app:${awssm:db-pass}@${gcpsm:proj/db-host}/app". Values without
+// valid queries pass through unchanged.
 //
 // The resolution process:
 //   1. Parse each value to identify secret queries
@@ -44,14 +60,48 @@ type variable struct {
 //   }
 //   resolved, err := ResolveAll(input)
 //
+// Options change how values are resolved. For example, WithStrictPrefix only resolves queries that start with
+// QueryPrefix.
+//
 // Returns an error if any secret resolution fails. Partial results are not returned on error.
-func ResolveAll(vars map[string]string) (map[string]string, error) {
+func ResolveAll(vars map[string]string, opts ...Option) (map[string]string, error) {
+	return ResolveAllContext(context.Background(), vars, opts...)
+}
+
+// ResolveAllContext works like ResolveAll, but gives up once ctx is done. Providers receive ctx, both when they are
+// created and when they resolve secrets. If ctx is done before all secrets are resolved, the returned error lists the
+// variables that were still pending.
+func ResolveAllContext(ctx context.Context, vars map[string]string, opts ...Option) (map[string]string, error) {
+	return NewResolver(opts...).ResolveAll(ctx, vars)
+}
+
+// resolveAll works like ResolveAllContext, but also returns the leases of all
+// secrets it resolved, and the values of those secrets, before and after
+// filtering. If resolution fails, resolveAll revokes those leases itself.
+func resolveAll(ctx context.Context, vars map[string]string, opts options) (map[string]string, []Lease, []string, error) {
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, opts.timeout,
+			fmt.Errorf("timed out after %s: %w", opts.timeout, context.DeadlineExceeded))
+		defer cancel()
+	}
+
+	// Interpolated variables go through the pipeline once per embedded query,
+	// so channels must be large enough for all of them.
+	capacity := 0
+	for _, value := range vars {
+		capacity += 1 + strings.Count(value, "${")
+	}
+
 	var (
 		rawVars  = make(chan variable, len(vars))
-		parsed   = make(chan variable, len(vars))
-		resolved = make(chan variable, len(vars))
-		done     = make(chan variable, len(vars))
-		failed   = make(chan variable, len(vars))
+		parsed   = make(chan variable, capacity)
+		resolved = make(chan variable, capacity)
+		done     = make(chan variable, capacity)
+		failed   = make(chan variable, capacity)
+		finished = make(chan struct{})
+
+		leases leaseSet
 	)
 
 	// First, feed all the environment variable into the pipeline.
@@ -68,80 +118,311 @@ func ResolveAll(vars map[string]string) (map[string]string, error) {
 	// Next, launch the first step of the pipeline: parsing.
 
 	go func() {
-		parseVariables(rawVars, parsed, done)
+		parseVariables(rawVars, parsed, done, failed, opts)
 		close(parsed)
 	}()
 
 	// Then, launch the second step of the pipeline: reference resolution.
 
 	go func() {
-		resolveVariables(parsed, resolved, failed)
+		resolveVariables(ctx, parsed, resolved, failed, &leases, opts)
 		close(resolved)
 	}()
 
 	// Next, launch the third step of the pipeline: filtering.
 
 	go func() {
-		filterVariables(resolved, done, failed)
+		filterVariables(resolved, done, failed, opts)
 		close(done)
 		close(failed)
+		close(finished)
 	}()
 
 	// Finally, drain the end of the pipeline and aggregate the results.
 
-	var multierr error
-	for v := range failed {
-		multierr = multierror.Append(multierr, fmt.Errorf("%s: %w", v.name, v.err))
+	var (
+		results, failures []variable
+
+		doneOut   <-chan variable = done
+		failedOut <-chan variable = failed
+	)
+	for doneOut != nil || failedOut != nil {
+		select {
+		case v, ok := <-doneOut:
+			if !ok {
+				doneOut = nil
+				continue
+			}
+			results = append(results, v)
+		case v, ok := <-failedOut:
+			if !ok {
+				failedOut = nil
+				continue
+			}
+			failures = append(failures, v)
+		case <-ctx.Done():
+			// Providers may ignore ctx, so we stop waiting for them. Leases
+			// obtained so far are revoked now, and any others once the
+			// providers are done.
+			revoked := leases.list()
+			revokeLeases(revoked, opts.logger)
+			go func() {
+				<-finished
+				revokeLeases(leases.list()[len(revoked):], opts.logger)
+			}()
+
+			errs := failuresError(failures)
+			for _, name := range pendingVariables(vars, results, failures) {
+				errs = append(errs, &ResolutionError{
+					Variable: name,
+					Phase:    PhaseResolve,
+					Err:      fmt.Errorf("still pending: %w", context.Cause(ctx)),
+				})
+			}
+			if len(errs) == 0 {
+				// Every variable finished right as ctx was done.
+				return nil, nil, nil, context.Cause(ctx)
+			}
+			errs.sort()
+			return nil, nil, nil, errs
+		}
 	}
 
-	if multierr != nil {
-		return nil, multierr
+	if len(failures) > 0 {
+		revokeLeases(leases.list(), opts.logger)
+		return nil, nil, nil, failuresError(failures)
 	}
 
 	newVars := make(map[string]string)
-	for v := range done {
-		newVars[v.name] = v.finalValue
+
+	var (
+		secrets        []string
+		interpolations = make(map[string]*interpolation)
+		fragments      = make(map[string][]string) // values of embedded queries
+	)
+	for _, v := range results {
+		// Fallback values come from queries, not secret stores.
+		if v.query != nil && !v.fallback {
+			secrets = append(secrets, v.resolvedValue, v.filteredValue)
+		}
+
+		if v.interpolation == nil {
+			newVars[v.name] = v.finalValue
+			continue
+		}
+
+		if _, ok := fragments[v.name]; !ok {
+			interpolations[v.name] = v.interpolation
+			fragments[v.name] = make([]string, len(v.interpolation.queries))
+		}
+		fragments[v.name][v.fragment] = v.finalValue
+	}
+
+	for name, interp := range interpolations {
+		newVars[name] = interp.render(fragments[name])
 	}
 
-	return newVars, nil
+	return newVars, leases.list(), secrets, nil
 }
 
-func parseVariables(rawVars <-chan variable, parsed, done chan<- variable) {
+// failuresError aggregates the errors of failed variables.
+func failuresError(failures []variable) ResolutionErrors {
+	errs := make(ResolutionErrors, 0, len(failures))
+	for _, v := range failures {
+		err := &ResolutionError{
+			Variable: v.name,
+			Phase:    v.phase,
+			Err:      v.err,
+		}
+		if v.interpolation != nil {
+			err.Query = v.fragment + 1
+		}
+		if v.query != nil {
+			err.ProviderID = v.query.providerID
+			err.Ref = v.query.secretRef
+		}
+		errs = append(errs, err)
+	}
+	errs.sort()
+	return errs
+}
+
+// pendingVariables returns the names of variables that have neither been
+// resolved nor failed yet, in alphabetical order.
+func pendingVariables(vars map[string]string, results, failures []variable) []string {
+	var (
+		finished  = make(map[string]bool)
+		fragments = make(map[string]int) // number of resolved embedded queries
+	)
+	for _, v := range failures {
+		finished[v.name] = true
+	}
+	for _, v := range results {
+		if v.interpolation == nil {
+			finished[v.name] = true
+			continue
+		}
+		fragments[v.name]++
+		if fragments[v.name] == len(v.interpolation.queries) {
+			finished[v.name] = true
+		}
+	}
+
+	var pending []string
+	for name := range vars {
+		if !finished[name] {
+			pending = append(pending, name)
+		}
+	}
+	sort.Strings(pending)
+
+	return pending
+}
+
+func parseVariables(rawVars <-chan variable, parsed, done, failed chan<- variable, opts options) {
 	for v := range rawVars {
-		q, err := parseQuery(v.rawValue)
-		if err != nil {
+		p := queryParser{opts: opts}
+
+		q, isQuery := p.parse(v.rawValue)
+
+		var (
+			interp          *interpolation
+			isInterpolation bool
+		)
+		switch {
+		case isQuery:
+			// Nothing else to look for.
+		case opts.interpolate:
+			interp, isInterpolation = parseInterpolation(v.rawValue, p.parse)
+		case len(p.errs)+len(p.warnings) > 0 && embedsQueries(v.rawValue, opts):
+			// The value looks like a query with a typo only because it starts
+			// with "${", so suggesting another provider would be misleading.
+			p = queryParser{opts: opts}
+			err := errors.New("value embeds queries, but interpolation is off; enable it with --interpolate or WithInterpolation")
+			if opts.failOnUnknown {
+				p.errs = append(p.errs, err)
+			} else {
+				p.warnings = append(p.warnings, err)
+			}
+		}
+
+		if len(p.errs) > 0 {
+			v.err = fmt.Errorf("invalid query: %w", errors.Join(p.errs...))
+			v.phase = PhaseParse
+			failed <- v
+			continue
+		}
+		for _, err := range p.warnings {
+			opts.logger.Warn("value looks like a query, leaving it as-is", "variable", v.name, "error", err)
+		}
+
+		switch {
+		case isQuery:
+			v.query = &q
+			parsed <- v
+		case isInterpolation:
+			// Each embedded query goes through the pipeline on its own, so
+			// that it benefits from concurrency and caching like any other.
+			for i := range interp.queries {
+				fragment := v
+				fragment.query = &interp.queries[i]
+				fragment.interpolation = interp
+				fragment.fragment = i
+				parsed <- fragment
+			}
+		default:
 			// The variable's value is not a murmur query, so we should leave
 			// it as is.
 			v.finalValue = v.rawValue
 			done <- v
-			continue
 		}
-		if _, known := ProviderFactories[q.providerID]; !known {
-			// The variable's value looks like a query but the provider is
-			// unknown. It probably isn't a query.
-			// ?(busser): should we log a message here?
-			v.finalValue = v.rawValue
-			done <- v
-			continue
+	}
+}
+
+// embedsQueries reports whether s embeds queries with the ${query} syntax, for
+// known providers and filters.
+func embedsQueries(s string, opts options) bool {
+	p := queryParser{opts: opts}
+	_, found := parseInterpolation(s, p.parse)
+	return found
+}
+
+// queryParser finds queries in a variable's value. It keeps track of values
+// that look like queries, but are not valid queries for known providers and
+// filters.
+type queryParser struct {
+	opts options
+
+	// Mistakes that make the variable invalid.
+	errs []error
+	// Mistakes that murmur only warns about.
+	warnings []error
+}
+
+// parse parses s as a query and reports whether murmur should resolve it.
+// Values starting with QueryPrefix are always queries, so they are invalid if
+// they are not valid queries. Other values are only queries if they are valid,
+// and if the parser is not in strict mode.
+func (p *queryParser) parse(s string) (query, bool) {
+	s, marked := strings.CutPrefix(s, QueryPrefix)
+	if !marked && p.opts.strictPrefix {
+		return query{}, false
+	}
+
+	q, err := parseQuery(s, p.opts.filters)
+	if err != nil {
+		if marked {
+			p.errs = append(p.errs, err)
 		}
-		if _, known := Filters[q.filterID]; q.filterID != "" && !known {
-			// The variable's value looks like a query but the filter is
-			// unknown. It probably isn't a query.
-			// ?(busser): should we log a message here?
-			v.finalValue = v.rawValue
-			done <- v
-			continue
+		return query{}, false
+	}
+
+	if nearMiss, err := checkKnownQuery(q, p.opts); err != nil {
+		switch {
+		case marked, nearMiss && p.opts.failOnUnknown:
+			p.errs = append(p.errs, err)
+		case nearMiss:
+			p.warnings = append(p.warnings, err)
+		}
+		return query{}, false
+	}
+
+	return q, true
+}
+
+// checkKnownQuery returns an error if a provider or filter of a query is not
+// known to murmur. It reports whether the query is a near miss, meaning it is
+// probably a query with a typo: either its provider is known, or it is close to
+// a known provider.
+func checkKnownQuery(q query, opts options) (nearMiss bool, err error) {
+	if _, known := opts.providers[q.providerID]; !known {
+		suggestion, found := suggest(q.providerID, keys(opts.providers))
+		if !found {
+			// The value looks like a query but the provider is unknown. It
+			// probably isn't a query.
+			return false, fmt.Errorf("unknown provider %q", q.providerID)
 		}
+		return true, fmt.Errorf("unknown provider %q, did you mean %q?", q.providerID, suggestion)
+	}
 
-		v.query = &q
-		parsed <- v
+	for _, step := range q.filters {
+		if _, known := opts.filters[step.filterID]; known {
+			continue
+		}
+		if suggestion, found := suggest(step.filterID, keys(opts.filters)); found {
+			return true, fmt.Errorf("unknown filter %q, did you mean %q?", step.filterID, suggestion)
+		}
+		return true, fmt.Errorf("unknown filter %q", step.filterID)
 	}
+
+	return false, nil
 }
 
 // resolveVariables drains `in` and, for each variable, attempts to resolve the
 // reference the query contains. Variables with successful resolutions are
 // pushed to `out`. Variables with failed resolutions are pushed to `failed`.
-func resolveVariables(in <-chan variable, out, failed chan<- variable) {
+// Leases of resolved secrets are added to `leases`.
+func resolveVariables(ctx context.Context, in <-chan variable, out, failed chan<- variable, leases *leaseSet, opts options) {
 	chanByProvider := make(map[string]chan variable)
 	var wg sync.WaitGroup
 
@@ -156,7 +437,7 @@ func resolveVariables(in <-chan variable, out, failed chan<- variable) {
 
 			wg.Add(1)
 			go func() {
-				resolveVariablesWithProvider(providerID, ch, out, failed)
+				resolveVariablesWithProvider(ctx, providerID, ch, out, failed, leases, opts)
 				wg.Done()
 			}()
 		}
@@ -174,19 +455,35 @@ func resolveVariables(in <-chan variable, out, failed chan<- variable) {
 // resolveVariablesWithProvider drains `int` and, for each variable, attempts to
 // resolve the reference the query contains with a specific provider. Variables
 // with successful resolutions are pushed to `out`. Variables with failed
-// resolutions are pushed to `failed`.
-func resolveVariablesWithProvider(providerID string, in <-chan variable, out, failed chan<- variable) {
-	provider, err := ProviderFactories[providerID]()
+// resolutions are pushed to `failed`. Leases of resolved secrets are added to
+// `leases`.
+func resolveVariablesWithProvider(ctx context.Context, providerID string, in <-chan variable, out, failed chan<- variable, leases *leaseSet, opts options) {
+	start := time.Now()
+	provider, err := opts.providers[providerID](ctx, opts.providerConfig(providerID))
 	if err != nil {
+		opts.logger.Debug("could not initialize provider", "provider", providerID, "duration", time.Since(start), "error", err)
 		// Since we cannot instanciate the provider, we return the same error
 		// for all variables sent our way.
 		for v := range in {
 			v.err = fmt.Errorf("provider instantiation error: %w", err)
+			v.phase = PhaseProviderInit
 			failed <- v
 		}
 		return
 	}
 	defer provider.Close()
+	opts.logger.Debug("initialized provider", "provider", providerID, "duration", time.Since(start))
+
+	if lp, ok := provider.(LeasingProvider); ok {
+		defer func() {
+			leases.add(lp.Leases()...)
+		}()
+	}
+
+	if bp, ok := provider.(BatchProvider); ok {
+		resolveVariablesWithBatchProvider(ctx, providerID, bp, in, out, failed, opts)
+		return
+	}
 
 	// To avoid querying the provider for the same secret twice, we keep a
 	// cache of resolved secrets. Since secrets are resolved concurrently,
@@ -206,6 +503,10 @@ func resolveVariablesWithProvider(providerID string, in <-chan variable, out, fa
 
 		mu    sync.Mutex // protects cache
 		cache = make(map[string]result)
+
+		// Limits how many secrets are fetched at once, to avoid tripping
+		// the secret store's rate limits.
+		inFlight = make(chan struct{}, opts.concurrencyFor(providerID))
 	)
 
 	for v := range in {
@@ -219,14 +520,31 @@ func resolveVariablesWithProvider(providerID string, in <-chan variable, out, fa
 		go func(v variable) {
 			defer wg.Done()
 
-			secretValue, err := provider.Resolve(context.TODO(), v.query.secretRef)
+			var (
+				secretValue string
+				err         error
+			)
+			select {
+			case inFlight <- struct{}{}:
+				start := time.Now()
+				secretValue, err = resolveWithRetry(ctx, provider, providerID, v.query.secretRef, opts)
+				<-inFlight
+				logResolution(opts.logger, providerID, v.query.secretRef, time.Since(start), err)
+			case <-ctx.Done():
+				err = context.Cause(ctx)
+			}
 
 			mu.Lock()
 			cache[v.query.secretRef] = result{secretValue, err}
 			mu.Unlock()
 
 			if err != nil {
+				if v.useFallback(err) {
+					out <- v
+					return
+				}
 				v.err = fmt.Errorf("could not resolve reference: %w", err)
+				v.phase = PhaseResolve
 				failed <- v
 				return
 			}
@@ -243,8 +561,14 @@ func resolveVariablesWithProvider(providerID string, in <-chan variable, out, fa
 
 	for _, v := range duplicates {
 		result := cache[v.query.secretRef]
+		opts.logger.Debug("cache hit", "provider", providerID, "ref", v.query.secretRef, "variable", v.name)
 		if result.err != nil {
-			v.err = fmt.Errorf("could not resolve reference: %w", err)
+			if v.useFallback(result.err) {
+				out <- v
+				continue
+			}
+			v.err = fmt.Errorf("could not resolve reference: %w", result.err)
+			v.phase = PhaseResolve
 			failed <- v
 			continue
 		}
@@ -254,64 +578,165 @@ func resolveVariablesWithProvider(providerID string, in <-chan variable, out, fa
 	}
 }
 
-// filterVariables drains `in` and, for each variable, attempts to filter the
-// the secret's value with the filtering rule contained in the query. Variables
+// resolveVariablesWithBatchProvider drains `in`, then resolves the references
+// the variables' queries contain all at once with a batch provider. Variables
 // with successful resolutions are pushed to `out`. Variables with failed
 // resolutions are pushed to `failed`.
-func filterVariables(in <-chan variable, out, failed chan<- variable) {
-	chanByFilter := make(map[string]chan variable)
-	var wg sync.WaitGroup
+func resolveVariablesWithBatchProvider(ctx context.Context, providerID string, provider BatchProvider, in <-chan variable, out, failed chan<- variable, opts options) {
+	var (
+		refs      []string
+		varsByRef = make(map[string][]variable)
+	)
 
 	for v := range in {
-		filterID := v.query.filterID
+		ref := v.query.secretRef
+		if _, ok := varsByRef[ref]; ok {
+			opts.logger.Debug("cache hit", "provider", providerID, "ref", ref, "variable", v.name)
+		} else {
+			refs = append(refs, ref)
+		}
+		varsByRef[ref] = append(varsByRef[ref], v)
+	}
 
-		if filterID == "" {
-			v.filteredValue = v.resolvedValue
-			v.finalValue = v.filteredValue
-			out <- v
+	if len(refs) == 0 {
+		return
+	}
+
+	start := time.Now()
+	values, errs := resolveBatchWithRetry(ctx, provider, providerID, refs, opts)
+	batchDuration := time.Since(start)
+
+	durations := make([]time.Duration, len(refs))
+	for i := range refs {
+		durations[i] = batchDuration
+	}
+
+	// References the provider could not resolve in a batch are resolved one
+	// by one, like with any other provider.
+	var (
+		wg       sync.WaitGroup
+		inFlight = make(chan struct{}, opts.concurrencyFor(providerID))
+	)
+	for i, ref := range refs {
+		if !errors.Is(errs[i], ErrNotBatched) {
 			continue
 		}
 
-		// Dispatch variable to separate goroutines based on the filter
-		// specified in the variable's query.
-		if _, ok := chanByFilter[filterID]; !ok {
-			ch := make(chan variable, cap(in))
-			chanByFilter[filterID] = ch
+		wg.Add(1)
+		go func(i int, ref string) {
+			defer wg.Done()
 
-			wg.Add(1)
-			go func() {
-				filterVariablesWithFilter(filterID, ch, out, failed)
-				wg.Done()
-			}()
+			select {
+			case inFlight <- struct{}{}:
+				start := time.Now()
+				values[i], errs[i] = resolveWithRetry(ctx, provider, providerID, ref, opts)
+				<-inFlight
+				durations[i] = time.Since(start)
+			case <-ctx.Done():
+				errs[i] = context.Cause(ctx)
+			}
+		}(i, ref)
+	}
+	wg.Wait()
+
+	for i, ref := range refs {
+		logResolution(opts.logger, providerID, ref, durations[i], errs[i])
+
+		for _, v := range varsByRef[ref] {
+			if errs[i] != nil {
+				if v.useFallback(errs[i]) {
+					out <- v
+					continue
+				}
+				v.err = fmt.Errorf("could not resolve reference: %w", errs[i])
+				v.phase = PhaseResolve
+				failed <- v
+				continue
+			}
+
+			v.resolvedValue = values[i]
+			out <- v
 		}
+	}
+}
 
-		chanByFilter[filterID] <- v
+// logResolution logs the outcome of resolving a reference, but never the
+// secret's value.
+func logResolution(logger *slog.Logger, providerID, ref string, duration time.Duration, err error) {
+	if err != nil {
+		logger.Debug("could not resolve reference", "provider", providerID, "ref", ref, "duration", duration, "error", err)
+		return
 	}
+	logger.Debug("resolved reference", "provider", providerID, "ref", ref, "duration", duration)
+}
 
-	// Wait for each filter to finish filtering its secrets.
-	for _, ch := range chanByFilter {
-		close(ch)
+// useFallback sets the variable's resolved value to its query's fallback value,
+// if the query has one and err means that the secret does not exist. It reports
+// whether it did.
+func (v *variable) useFallback(err error) bool {
+	if !v.query.optional || !errors.Is(err, ErrNotFound) {
+		return false
 	}
-	wg.Wait()
+
+	v.resolvedValue = v.query.defaultValue
+	v.fallback = true
+
+	return true
 }
 
-// filterVariablesWithFilter drains `in` and, for each variable, attempts to
-// filter the the secret's value with a specific filter. Variables with
-// successful resolutions are pushed to `out`. Variables with failed resolutions
-// are pushed to `failed`.
-func filterVariablesWithFilter(filterID string, in <-chan variable, out, failed chan<- variable) {
-	filter := Filters[filterID]
+// filterVariables drains `in` and, for each variable, attempts to filter the
+// the secret's value with the filter steps contained in the query, in order.
+// Variables with successful resolutions are pushed to `out`. Variables with
+// failed resolutions are pushed to `failed`.
+func filterVariables(in <-chan variable, out, failed chan<- variable, opts options) {
+	var wg sync.WaitGroup
 
 	for v := range in {
-		filteredValue, err := filter(v.resolvedValue, v.query.filterRule)
+		wg.Add(1)
+		go func(v variable) {
+			defer wg.Done()
+
+			// Fallback values are used as-is.
+			if v.fallback {
+				v.filteredValue = v.resolvedValue
+				v.finalValue = v.filteredValue
+				out <- v
+				return
+			}
+
+			filteredValue, err := applyFilters(v.resolvedValue, v.query.filters, opts.filters)
+			if err != nil {
+				v.err = fmt.Errorf("could not filter value: %w", err)
+				v.phase = PhaseFilter
+				failed <- v
+				return
+			}
+			for _, step := range v.query.filters {
+				opts.logger.Debug("applied filter", "variable", v.name, "filter", step.filterID)
+			}
+
+			v.filteredValue = filteredValue
+			v.finalValue = v.filteredValue
+			out <- v
+		}(v)
+	}
+
+	// Wait for all variables to be filtered.
+	wg.Wait()
+}
+
+// applyFilters runs value through each filter step in order. The output of a
+// step is the input of the next.
+func applyFilters(value string, steps []filterStep, filters map[string]Filter) (string, error) {
+	for i, step := range steps {
+		filter := filters[step.filterID]
+
+		var err error
+		value, err = filter(value, step.filterRule)
 		if err != nil {
-			v.err = fmt.Errorf("could not filter value: %w", err)
-			failed <- v
-			continue
+			return "", fmt.Errorf("step %d (%s): %w", i+1, step.filterID, err)
 		}
-
-		v.filteredValue = filteredValue
-		v.finalValue = v.filteredValue
-		out <- v
 	}
+
+	return value, nil
 }
@@ -1,9 +1,17 @@
 package murmur
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/busser/murmur/pkg/murmur/providers/flakymock"
 	"github.com/busser/murmur/pkg/murmur/providers/jsonmock"
 	"github.com/busser/murmur/pkg/murmur/providers/mock"
 	"github.com/busser/murmur/pkg/slices"
@@ -16,10 +24,19 @@ type MockProvider interface {
 	Closed() bool
 }
 
+// fastRetries retries transient errors without slowing tests down.
+var fastRetries = WithRetryPolicy(RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     5 * time.Millisecond,
+	Jitter:         0.5,
+})
+
 func TestResolveAll(t *testing.T) {
 	tt := []struct {
 		name      string
 		providers map[string]MockProvider
+		opts      []Option
 		variables map[string]string
 		want      map[string]string
 	}{
@@ -72,6 +89,137 @@ func TestResolveAll(t *testing.T) {
 				"C": "is my ref C?",
 			},
 		},
+		{
+			name: "filter chains",
+			providers: map[string]MockProvider{
+				"json": jsonmock.New(),
+			},
+			variables: map[string]string{
+				"A": "json:A|jsonpath:{ ." + jsonmock.Key + " }|base64:encode",
+				"B": "json:B|base64:encode|base64:decode|jsonpath:ref={ ." + jsonmock.Key + " }",
+			},
+			want: map[string]string{
+				"A": "QQ==",
+				"B": "ref=B",
+			},
+		},
+		{
+			name: "interpolation",
+			providers: map[string]MockProvider{
+				"foo":  mock.New(),
+				"bar":  mock.New(),
+				"json": jsonmock.New(),
+			},
+			opts: []Option{WithInterpolation()},
+			variables: map[string]string{
+				"A": "user=${foo:user} password=${bar:password}",
+				"B": "${foo:user}:${foo:user}@${json:host|jsonpath:{ ." + jsonmock.Key + " }}",
+				"C": "foo:user",
+				"D": "$${foo:user} is not ${HOME}",
+				"E": "${baz:user} is unknown, but ${foo:user} is not",
+			},
+			want: map[string]string{
+				"A": "user=" + mock.ValueFor("user") + " password=" + mock.ValueFor("password"),
+				"B": mock.ValueFor("user") + ":" + mock.ValueFor("user") + "@host",
+				"C": mock.ValueFor("user"),
+				"D": "$${foo:user} is not ${HOME}",
+				"E": "${baz:user} is unknown, but " + mock.ValueFor("user") + " is not",
+			},
+		},
+		{
+			name: "interpolation disabled",
+			providers: map[string]MockProvider{
+				"foo": mock.New(),
+			},
+			variables: map[string]string{
+				"A": "user=${foo:user}",
+				"B": "${foo:-/tmp}",
+				"C": "foo:user",
+			},
+			want: map[string]string{
+				"A": "user=${foo:user}",
+				"B": "${foo:-/tmp}",
+				"C": mock.ValueFor("user"),
+			},
+		},
+		{
+			name: "optional references",
+			providers: map[string]MockProvider{
+				"foo": mock.New(),
+			},
+			opts: []Option{WithInterpolation()},
+			variables: map[string]string{
+				"A": "foo:NOT_FOUND?optional",
+				"B": "foo:NOT_FOUND?default=off",
+				"C": "foo:NOT_FOUND?default=not json|jsonpath:{ .missing }",
+				"D": "foo:flag?default=off",
+				"E": "flag=${foo:NOT_FOUND?default=off}",
+			},
+			want: map[string]string{
+				"A": "",
+				"B": "off",
+				"C": "not json",
+				"D": mock.ValueFor("flag"),
+				"E": "flag=off",
+			},
+		},
+		{
+			name: "query prefix",
+			providers: map[string]MockProvider{
+				"foo": mock.New(),
+			},
+			opts: []Option{WithInterpolation()},
+			variables: map[string]string{
+				"A": "murmur+foo:A",
+				"B": "foo:B",
+				"C": "user=${murmur+foo:C}",
+			},
+			want: map[string]string{
+				"A": mock.ValueFor("A"),
+				"B": mock.ValueFor("B"),
+				"C": "user=" + mock.ValueFor("C"),
+			},
+		},
+		{
+			name: "strict prefix",
+			providers: map[string]MockProvider{
+				"foo": mock.New(),
+			},
+			opts: []Option{WithStrictPrefix(), WithInterpolation()},
+			variables: map[string]string{
+				"A": "murmur+foo:A",
+				"B": "foo:B",
+				"C": "user=${murmur+foo:C} password=${foo:D}",
+				"D": "murmur+foo:NOT_FOUND?default=off",
+			},
+			want: map[string]string{
+				"A": mock.ValueFor("A"),
+				"B": "foo:B",
+				"C": "user=" + mock.ValueFor("C") + " password=${foo:D}",
+				"D": "off",
+			},
+		},
+		{
+			name: "near misses",
+			providers: map[string]MockProvider{
+				"foo":  mock.New(),
+				"json": jsonmock.New(),
+			},
+			variables: map[string]string{
+				"A": "fooo:A",
+				"B": "json:B|jsonpth:{ ." + jsonmock.Key + " }",
+				"C": "user=${fo:C}",
+				"D": "foo:D",
+				"E": "json:E|jsonpath:{ ." + jsonmock.Key + " }",
+			},
+			want: map[string]string{
+				"A": "fooo:A",
+				"B": "json:B|jsonpth:{ ." + jsonmock.Key + " }",
+				"C": "user=${fo:C}",
+				"D": mock.ValueFor("D"),
+				"E": "E",
+			},
+		},
 		{
 			name: "caching",
 			providers: map[string]MockProvider{
@@ -118,22 +266,38 @@ func TestResolveAll(t *testing.T) {
 				"SAME_JSON_SECRET":    "ref=cloud credentials",
 			},
 		},
+		{
+			name: "transient errors",
+			providers: map[string]MockProvider{
+				"flaky": flakymock.New(2),
+			},
+			opts: []Option{fastRetries},
+			variables: map[string]string{
+				"A": "flaky:A",
+				"B": "flaky:B",
+				"C": "flaky:A",
+			},
+			want: map[string]string{
+				"A": flakymock.ValueFor("A"),
+				"B": flakymock.ValueFor("B"),
+				"C": flakymock.ValueFor("A"),
+			},
+		},
 	}
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
 			factories := make(map[string]ProviderFactory)
 			for prefix, provider := range tc.providers {
 				provider := provider
-				factories[prefix] = func() (Provider, error) { return provider, nil }
+				factories[prefix] = func(context.Context, ProviderConfig) (Provider, error) { return provider, nil }
 			}
 
-			// Replace murmur's clients with mocks for the duration of the test.
-			originalProviderFactories := ProviderFactories
-			defer func() { ProviderFactories = originalProviderFactories }()
-			ProviderFactories = factories
+			opts := append([]Option{WithProviders(factories)}, tc.opts...)
 
-			actual, err := ResolveAll(tc.variables)
+			actual, err := ResolveAll(tc.variables, opts...)
 			if err != nil {
 				t.Fatalf("ResolveAll() returned an error: %v", err)
 			}
@@ -159,6 +323,7 @@ func TestResolveAllWithError(t *testing.T) {
 	tt := []struct {
 		name       string
 		providers  map[string]MockProvider
+		opts       []Option
 		variables  map[string]string
 		wantOK     []string
 		wantFailed []string
@@ -170,35 +335,84 @@ func TestResolveAllWithError(t *testing.T) {
 				"bar":  mock.New(),
 				"json": jsonmock.New(),
 			},
+			opts: []Option{WithInterpolation()},
 			variables: map[string]string{
 				"NOT_A_SECRET":        "My app listens on port 3000",
 				"OK_SECRET":           "foo:database password",
 				"BROKEN_SECRET":       "foo:FAIL",
+				"SAME_BROKEN_SECRET":  "foo:FAIL",
 				"BUGGY_SECRET":        "bar:FAIL",
 				"LOOKS_LIKE_A_SECRET": "baz:FAIL",
 				"JSON_ERR":            "json:cloud credentials|jsonpath:{ .missing }",
 				"NOT_JSON":            "foo:api key|jsonpath:{ .foo }",
 				"OK_JSON":             "json:cloud credentials|jsonpath:{ ." + jsonmock.Key + " }",
+				"CHAIN_ERR":           "json:cloud credentials|jsonpath:{ ." + jsonmock.Key + " }|base64:decode",
+				"INTERPOLATED_ERR":    "ok=${foo:database password} broken=${foo:FAIL}",
+				"MISSING_SECRET":      "foo:NOT_FOUND",
+				"OPTIONAL_BROKEN":     "bar:FAIL?optional",
+				"UNKNOWN_PREFIXED":    "murmur+baz:FAIL",
+				"INVALID_PREFIXED":    "murmur+foo:",
 			},
 			wantOK:     []string{"NOT_A_SECRET", "OK_SECRET", "LOOKS_LIKE_A_SECRET", "OK_JSON"},
-			wantFailed: []string{"BROKEN_SECRET", "BUGGY_SECRET", "JSON_ERR", "NOT_JSON"},
+			wantFailed: []string{"BROKEN_SECRET", "SAME_BROKEN_SECRET: could not resolve reference: " + mock.ErrorFor("FAIL").Error(), "BUGGY_SECRET", "JSON_ERR", "NOT_JSON", "CHAIN_ERR: could not filter value: step 2 (base64)", "INTERPOLATED_ERR: embedded query 2", "MISSING_SECRET", "OPTIONAL_BROKEN", "UNKNOWN_PREFIXED: invalid query", "INVALID_PREFIXED: invalid query"},
+		},
+		{
+			name: "fail on unknown",
+			providers: map[string]MockProvider{
+				"foo":  mock.New(),
+				"json": jsonmock.New(),
+			},
+			opts: []Option{WithFailOnUnknown(), WithInterpolation()},
+			variables: map[string]string{
+				"OK_SECRET":        "foo:database password",
+				"OK_JSON":          "json:credentials|jsonpath:{ ." + jsonmock.Key + " }",
+				"NOT_A_SECRET":     "https://example.com",
+				"TYPO_PROVIDER":    "fooo:database password",
+				"TYPO_FILTER":      "json:credentials|jsonpth:{ ." + jsonmock.Key + " }",
+				"UNKNOWN_FILTER":   "json:credentials|xpath:/password",
+				"TYPO_INTERPOLATE": "user=${fo:user}",
+			},
+			wantOK: []string{"OK_SECRET", "OK_JSON", "NOT_A_SECRET"},
+			wantFailed: []string{
+				`TYPO_PROVIDER: invalid query: unknown provider "fooo", did you mean "foo"?`,
+				`TYPO_FILTER: invalid query: unknown filter "jsonpth", did you mean "jsonpath"?`,
+				`UNKNOWN_FILTER: invalid query: unknown filter "xpath"`,
+				`TYPO_INTERPOLATE: invalid query: unknown provider "fo", did you mean "foo"?`,
+			},
+		},
+		{
+			name: "too many transient errors",
+			providers: map[string]MockProvider{
+				"foo":   mock.New(),
+				"flaky": flakymock.New(3),
+			},
+			opts: []Option{fastRetries},
+			variables: map[string]string{
+				"OK_SECRET":     "foo:database password",
+				"BROKEN_SECRET": "foo:FAIL",
+				"FLAKY_SECRET":  "flaky:api key",
+			},
+			wantOK: []string{"OK_SECRET"},
+			wantFailed: []string{
+				"FLAKY_SECRET: could not resolve reference: gave up after 3 attempts: " + flakymock.ErrorFor("api key").Error(),
+				"BROKEN_SECRET: could not resolve reference: " + mock.ErrorFor("FAIL").Error(),
+			},
 		},
 	}
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
 			factories := make(map[string]ProviderFactory)
 			for prefix, provider := range tc.providers {
 				provider := provider
-				factories[prefix] = func() (Provider, error) { return provider, nil }
+				factories[prefix] = func(context.Context, ProviderConfig) (Provider, error) { return provider, nil }
 			}
 
-			// Replace murmur's clients with mocks for the duration of the test.
-			originalProviderFactories := ProviderFactories
-			defer func() { ProviderFactories = originalProviderFactories }()
-			ProviderFactories = factories
+			opts := append([]Option{WithProviders(factories)}, tc.opts...)
 
-			_, err := ResolveAll(tc.variables)
+			_, err := ResolveAll(tc.variables, opts...)
 			if err == nil {
 				t.Fatal("ResolveAll() returned no error but it should have")
 			}
@@ -229,3 +443,417 @@ func TestResolveAllWithError(t *testing.T) {
 		})
 	}
 }
+
+// hangingProvider never resolves any secret, and ignores its context.
+type hangingProvider struct {
+	release chan struct{}
+}
+
+func (p *hangingProvider) Resolve(ctx context.Context, ref string) (string, error) {
+	<-p.release
+	return "", errors.New("released")
+}
+
+func (p *hangingProvider) Close() error {
+	return nil
+}
+
+func TestResolveAllContext(t *testing.T) {
+	t.Parallel()
+
+	hanging := &hangingProvider{release: make(chan struct{})}
+	defer close(hanging.release)
+
+	factoryCtx := make(chan context.Context, 1)
+	factories := map[string]ProviderFactory{
+		"foo": func(context.Context, ProviderConfig) (Provider, error) { return mock.New(), nil },
+		"hang": func(ctx context.Context, _ ProviderConfig) (Provider, error) {
+			factoryCtx <- ctx
+			return hanging, nil
+		},
+	}
+
+	variables := map[string]string{
+		"NOT_A_SECRET": "My app listens on port 3000",
+		"OK_SECRET":    "foo:database password",
+		"HANGING":      "hang:database password",
+		"INTERPOLATED": "ok=${foo:api key} hanging=${hang:api key}",
+		"BROKEN":       "foo:FAIL",
+	}
+
+	start := time.Now()
+	_, err := ResolveAllContext(context.Background(), variables, WithProviders(factories), WithInterpolation(), WithTimeout(50*time.Millisecond))
+	if err == nil {
+		t.Fatal("ResolveAllContext() returned no error but it should have")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("ResolveAllContext() took %s to give up", elapsed)
+	}
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("errors.Is(err, context.DeadlineExceeded) = false, want true")
+	}
+	if ctx := <-factoryCtx; ctx.Err() == nil {
+		t.Error("provider factory did not receive the resolution's context")
+	}
+
+	errMsg := err.Error()
+
+	for _, s := range []string{"NOT_A_SECRET", "OK_SECRET"} {
+		if strings.Contains(errMsg, s) {
+			t.Errorf("Error message %q should not mention %q", errMsg, s)
+		}
+	}
+
+	for _, s := range []string{
+		"HANGING: still pending: timed out after 50ms",
+		"INTERPOLATED: still pending: timed out after 50ms",
+		"BROKEN: could not resolve reference",
+	} {
+		if !strings.Contains(errMsg, s) {
+			t.Errorf("Error message %q should mention %q", errMsg, s)
+		}
+	}
+}
+
+// slowProvider takes a while to resolve each secret, and records how many
+// secrets it resolves at once.
+type slowProvider struct {
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (p *slowProvider) Resolve(ctx context.Context, ref string) (string, error) {
+	p.mu.Lock()
+	p.inFlight++
+	p.maxInFlight = max(p.maxInFlight, p.inFlight)
+	p.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	p.mu.Lock()
+	p.inFlight--
+	p.mu.Unlock()
+
+	return ref, nil
+}
+
+func (p *slowProvider) Close() error {
+	return nil
+}
+
+func TestResolveAllConcurrency(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name        string
+		concurrency map[string]int
+		opts        []Option
+		want        int
+	}{
+		{
+			name: "default limit",
+			want: DefaultProviderConcurrency,
+		},
+		{
+			name:        "per-provider limit",
+			concurrency: map[string]int{"slow": 3},
+			want:        3,
+		},
+		{
+			name:        "global limit",
+			concurrency: map[string]int{"slow": 3},
+			opts:        []Option{WithMaxConcurrency(5)},
+			want:        5,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			slow := &slowProvider{}
+
+			opts := append([]Option{
+				WithProviders(map[string]ProviderFactory{
+					"slow": func(context.Context, ProviderConfig) (Provider, error) { return slow, nil },
+				}),
+			}, tc.opts...)
+			for id, n := range tc.concurrency {
+				opts = append(opts, WithProviderConcurrency(id, n))
+			}
+
+			variables := make(map[string]string)
+			for i := range 40 {
+				variables[fmt.Sprintf("SECRET_%d", i)] = fmt.Sprintf("slow:secret %d", i)
+			}
+
+			if _, err := ResolveAll(variables, opts...); err != nil {
+				t.Fatalf("ResolveAll() returned an error: %v", err)
+			}
+
+			if slow.maxInFlight != tc.want {
+				t.Errorf("provider resolved up to %d secrets at once, want %d", slow.maxInFlight, tc.want)
+			}
+		})
+	}
+}
+
+// batchMock turns a mock provider into a batch provider, and records the
+// batches it resolves.
+type batchMock struct {
+	MockProvider
+
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (p *batchMock) ResolveBatch(ctx context.Context, refs []string) ([]string, []error) {
+	p.mu.Lock()
+	p.batches = append(p.batches, refs)
+	p.mu.Unlock()
+
+	values := make([]string, len(refs))
+	errs := make([]error, len(refs))
+	for i, ref := range refs {
+		values[i], errs[i] = p.Resolve(ctx, ref)
+	}
+
+	return values, errs
+}
+
+func TestResolveAllBatch(t *testing.T) {
+	t.Parallel()
+
+	batch := &batchMock{MockProvider: mock.New()}
+	flaky := &batchMock{MockProvider: flakymock.New(1)}
+
+	factories := map[string]ProviderFactory{
+		"batch": func(context.Context, ProviderConfig) (Provider, error) { return batch, nil },
+		"flaky": func(context.Context, ProviderConfig) (Provider, error) { return flaky, nil },
+	}
+
+	variables := map[string]string{
+		"NOT_A_SECRET":  "My app listens on port 3000",
+		"FIRST_SECRET":  "batch:database password",
+		"SECOND_SECRET": "batch:api key",
+		"SAME_SECRET":   "batch:api key",
+		"INTERPOLATED":  "key=${batch:api key} user=${batch:user}",
+		"OPTIONAL":      "batch:NOT_FOUND?default=none",
+		"FLAKY_SECRET":  "flaky:private key",
+	}
+
+	actual, err := ResolveAll(variables, WithProviders(factories), WithInterpolation(), fastRetries)
+	if err != nil {
+		t.Fatalf("ResolveAll() returned an error: %v", err)
+	}
+
+	want := map[string]string{
+		"NOT_A_SECRET":  "My app listens on port 3000",
+		"FIRST_SECRET":  mock.ValueFor("database password"),
+		"SECOND_SECRET": mock.ValueFor("api key"),
+		"SAME_SECRET":   mock.ValueFor("api key"),
+		"INTERPOLATED":  "key=" + mock.ValueFor("api key") + " user=" + mock.ValueFor("user"),
+		"OPTIONAL":      "none",
+		"FLAKY_SECRET":  flakymock.ValueFor("private key"),
+	}
+	if diff := cmp.Diff(want, actual); diff != "" {
+		t.Errorf("ResolveAll() mismatch (-want +got):\n%s", diff)
+	}
+
+	for prefix, provider := range map[string]*batchMock{"batch": batch, "flaky": flaky} {
+		if !provider.Closed() {
+			t.Errorf("%q provider not closed", prefix)
+		}
+		if slices.Duplicates(provider.ResolvedRefs()) != 0 {
+			t.Errorf("%q provider resolved the same reference more than once, is caching broken?", prefix)
+			t.Logf("%q provider resolved: %q", prefix, provider.ResolvedRefs())
+		}
+	}
+
+	if len(batch.batches) != 1 || len(batch.batches[0]) != 4 {
+		t.Errorf("provider resolved batches %q, want a single batch of 4 references", batch.batches)
+	}
+	if len(flaky.batches) != 2 {
+		t.Errorf("flaky provider resolved batches %q, want the batch retried once", flaky.batches)
+	}
+}
+
+// unbatchedProvider is a batch provider that cannot resolve anything in a
+// batch, like AWS Secrets Manager without the permission to get secrets in
+// batches.
+type unbatchedProvider struct {
+	slowProvider
+
+	mu      sync.Mutex
+	batches int
+}
+
+func (p *unbatchedProvider) ResolveBatch(ctx context.Context, refs []string) ([]string, []error) {
+	p.mu.Lock()
+	p.batches++
+	p.mu.Unlock()
+
+	errs := make([]error, len(refs))
+	for i := range refs {
+		errs[i] = ErrNotBatched
+	}
+	return make([]string, len(refs)), errs
+}
+
+func TestResolveAllNotBatched(t *testing.T) {
+	t.Parallel()
+
+	unbatched := &unbatchedProvider{}
+
+	variables := make(map[string]string)
+	want := make(map[string]string)
+	for i := range 40 {
+		name := fmt.Sprintf("SECRET_%d", i)
+		variables[name] = fmt.Sprintf("unbatched:secret %d", i)
+		want[name] = fmt.Sprintf("secret %d", i)
+	}
+
+	actual, err := ResolveAll(variables,
+		WithProviders(map[string]ProviderFactory{
+			"unbatched": func(context.Context, ProviderConfig) (Provider, error) { return unbatched, nil },
+		}),
+		WithProviderConcurrency("unbatched", 3),
+	)
+	if err != nil {
+		t.Fatalf("ResolveAll() returned an error: %v", err)
+	}
+	if diff := cmp.Diff(want, actual); diff != "" {
+		t.Errorf("ResolveAll() mismatch (-want +got):\n%s", diff)
+	}
+
+	if unbatched.batches != 1 {
+		t.Errorf("provider resolved %d batches, want 1", unbatched.batches)
+	}
+	// References left out of the batch are resolved concurrently, within the
+	// provider's limit.
+	if unbatched.maxInFlight != 3 {
+		t.Errorf("provider resolved up to %d secrets at once, want 3", unbatched.maxInFlight)
+	}
+}
+
+func TestResolveAllResolutionErrors(t *testing.T) {
+	t.Parallel()
+
+	factories := map[string]ProviderFactory{
+		"foo":    func(context.Context, ProviderConfig) (Provider, error) { return mock.New(), nil },
+		"json":   func(context.Context, ProviderConfig) (Provider, error) { return jsonmock.New(), nil },
+		"broken": func(context.Context, ProviderConfig) (Provider, error) { return nil, errors.New("no credentials") },
+	}
+
+	variables := map[string]string{
+		"OK_SECRET":      "foo:database password",
+		"INVALID":        "murmur+foo:",
+		"NO_CREDENTIALS": "broken:api key",
+		"MISSING":        "foo:NOT_FOUND",
+		"SAME_MISSING":   "foo:NOT_FOUND",
+		"NOT_JSON":       "foo:api key|jsonpath:{ .foo }",
+		"INTERPOLATED":   "ok=${foo:database password} broken=${foo:FAIL}",
+	}
+
+	_, err := ResolveAll(variables, WithProviders(factories), WithInterpolation())
+
+	var resErrs ResolutionErrors
+	if !errors.As(err, &resErrs) {
+		t.Fatalf("ResolveAll() returned %T, want ResolutionErrors", err)
+	}
+
+	want := []ResolutionError{
+		{Variable: "INTERPOLATED", Query: 2, ProviderID: "foo", Ref: "FAIL", Phase: PhaseResolve},
+		{Variable: "INVALID", Phase: PhaseParse},
+		{Variable: "MISSING", ProviderID: "foo", Ref: "NOT_FOUND", Phase: PhaseResolve},
+		{Variable: "NOT_JSON", ProviderID: "foo", Ref: "api key", Phase: PhaseFilter},
+		{Variable: "NO_CREDENTIALS", ProviderID: "broken", Ref: "api key", Phase: PhaseProviderInit},
+		{Variable: "SAME_MISSING", ProviderID: "foo", Ref: "NOT_FOUND", Phase: PhaseResolve},
+	}
+
+	actual := make([]ResolutionError, len(resErrs))
+	for i, e := range resErrs {
+		if e.Err == nil {
+			t.Errorf("error for %s has no underlying error", e.Variable)
+		}
+		actual[i] = *e
+		actual[i].Err = nil
+	}
+	if diff := cmp.Diff(want, actual); diff != "" {
+		t.Errorf("ResolveAll() errors mismatch (-want +got):\n%s", diff)
+	}
+
+	// Underlying errors are reachable from the aggregate error, including for
+	// duplicate references.
+	if !errors.Is(err, ErrNotFound) {
+		t.Error("errors.Is(err, ErrNotFound) is false, want true")
+	}
+	var resErr *ResolutionError
+	if !errors.As(err, &resErr) {
+		t.Error("errors.As(err, *ResolutionError) is false, want true")
+	}
+	for _, e := range resErrs {
+		if e.Phase == PhaseResolve && e.Ref == "NOT_FOUND" && !errors.Is(e, ErrNotFound) {
+			t.Errorf("error for %s does not wrap ErrNotFound: %v", e.Variable, e)
+		}
+	}
+}
+
+func TestResolveAllFileURLs(t *testing.T) {
+	t.Parallel()
+
+	// Values with a file: URL are common, and must not be mistaken for queries
+	// for the local file provider.
+	variables := map[string]string{
+		"SPRING_CONFIG_LOCATION": "file:/etc/",
+		"HOSTNAME_FILE":          "file:///etc/hostname",
+	}
+
+	actual, err := ResolveAll(variables)
+	if err != nil {
+		t.Fatalf("ResolveAll() returned an error: %v", err)
+	}
+	if diff := cmp.Diff(variables, actual); diff != "" {
+		t.Errorf("ResolveAll() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestResolveAllEmbeddedQueriesWithoutInterpolation(t *testing.T) {
+	t.Parallel()
+
+	providers := WithProviders(map[string]ProviderFactory{
+		"awssm": func(context.Context, ProviderConfig) (Provider, error) { return mock.New(), nil },
+	})
+	variables := map[string]string{
+		"PASSWORD": "${awssm:db-pass}",
+	}
+
+	// Murmur suggests enabling interpolation, rather than fixing a typo.
+
+	var logs bytes.Buffer
+	actual, err := ResolveAll(variables, providers, WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	if err != nil {
+		t.Fatalf("ResolveAll() returned an error: %v", err)
+	}
+	if diff := cmp.Diff(variables, actual); diff != "" {
+		t.Errorf("ResolveAll() mismatch (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(logs.String(), "interpolation is off") {
+		t.Errorf("ResolveAll() did not suggest enabling interpolation, logs:\n%s", logs.String())
+	}
+	if strings.Contains(logs.String(), "did you mean") {
+		t.Errorf("ResolveAll() suggested another provider, logs:\n%s", logs.String())
+	}
+
+	// Same when failing on unknown queries.
+
+	_, err = ResolveAll(variables, providers, WithFailOnUnknown())
+	if err == nil {
+		t.Fatal("ResolveAll() returned no error but it should have")
+	}
+	if !strings.Contains(err.Error(), "interpolation is off") || strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("ResolveAll() error %q should suggest enabling interpolation", err)
+	}
+}
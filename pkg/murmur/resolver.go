@@ -0,0 +1,35 @@
+package murmur
+
+import "context"
+
+// A Resolver resolves secrets with its own providers, filters, and settings.
+// Several resolvers with different options can be used in the same process,
+// and each is safe for concurrent use.
+//
+// By default, a Resolver uses copies of ProviderFactories, Filters, and
+// ProviderConcurrency, taken when the Resolver is created. Options like
+// WithProviders and WithFilters change that:
+//
+//	r := murmur.NewResolver(
+//	    murmur.WithProvider("mystore", newMyStoreProvider),
+//	    murmur.WithMaxConcurrency(4),
+//	    murmur.WithTimeout(30*time.Second),
+//	)
+//	resolved, err := r.ResolveAll(ctx, vars)
+type Resolver struct {
+	opts options
+}
+
+// NewResolver returns a Resolver configured with the given options.
+func NewResolver(opts ...Option) *Resolver {
+	return &Resolver{
+		opts: newOptions(opts),
+	}
+}
+
+// ResolveAll works like the package-level ResolveAllContext, with the
+// resolver's options.
+func (r *Resolver) ResolveAll(ctx context.Context, vars map[string]string) (map[string]string, error) {
+	newVars, _, _, err := resolveAll(ctx, vars, r.opts)
+	return newVars, err
+}
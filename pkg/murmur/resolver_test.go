@@ -0,0 +1,99 @@
+package murmur
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/busser/murmur/pkg/murmur/providers/mock"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResolver(t *testing.T) {
+	t.Parallel()
+
+	var logs bytes.Buffer
+
+	upper := func(value, rule string) (string, error) {
+		return strings.ToUpper(value), nil
+	}
+
+	plain := NewResolver(
+		WithProviders(map[string]ProviderFactory{
+			"foo": func(context.Context, ProviderConfig) (Provider, error) { return mock.New(), nil },
+		}),
+	)
+	custom := NewResolver(
+		WithProviders(map[string]ProviderFactory{
+			"foo": func(context.Context, ProviderConfig) (Provider, error) { return mock.New(), nil },
+		}),
+		WithProvider("bar", func(context.Context, ProviderConfig) (Provider, error) { return mock.New(), nil }),
+		WithFilter("upper", upper),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	)
+
+	variables := map[string]string{
+		"FOO":     "foo:database password",
+		"BAR":     "bar:api key",
+		"UPPER":   "foo:database password|upper:all",
+		"TYPO":    "fooo:api key",
+		"AWSSM":   "awssm:secret-sauce",
+		"LITERAL": "My app listens on port 3000",
+	}
+
+	// Each resolver only knows about its own providers and filters, even when
+	// both are used at the same time.
+
+	for _, tc := range []struct {
+		name     string
+		resolver *Resolver
+		want     map[string]string
+	}{
+		{
+			name:     "plain",
+			resolver: plain,
+			want: map[string]string{
+				"FOO":     mock.ValueFor("database password"),
+				"BAR":     "bar:api key",
+				"UPPER":   "foo:database password|upper:all",
+				"TYPO":    "fooo:api key",
+				"AWSSM":   "awssm:secret-sauce",
+				"LITERAL": "My app listens on port 3000",
+			},
+		},
+		{
+			name:     "custom",
+			resolver: custom,
+			want: map[string]string{
+				"FOO":     mock.ValueFor("database password"),
+				"BAR":     mock.ValueFor("api key"),
+				"UPPER":   strings.ToUpper(mock.ValueFor("database password")),
+				"TYPO":    "fooo:api key",
+				"AWSSM":   "awssm:secret-sauce",
+				"LITERAL": "My app listens on port 3000",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := tc.resolver.ResolveAll(context.Background(), variables)
+			if err != nil {
+				t.Fatalf("ResolveAll() returned an error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.want, actual); diff != "" {
+				t.Errorf("ResolveAll() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Cleanup(func() {
+		// The custom resolver warns about the typo on its own logger.
+		if !strings.Contains(logs.String(), `msg="value looks like a query, leaving it as-is" variable=TYPO`) {
+			t.Errorf("custom resolver did not log a warning about TYPO, logs:\n%s", logs.String())
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package murmur
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"time"
+
+	"github.com/busser/murmur/pkg/murmur/providers"
+)
+
+// ErrTransient means that a provider failed for a reason that may go away if
+// murmur tries again. See providers.ErrTransient.
+var ErrTransient = providers.ErrTransient
+
+// A RetryPolicy controls how murmur retries resolutions that fail with
+// transient errors, like throttling, server errors, or dropped connections.
+// Other errors, like missing secrets or denied permissions, are never retried.
+type RetryPolicy struct {
+	// MaxAttempts is how many times murmur tries to resolve a secret, including
+	// the first attempt. A value of 1 or less disables retries.
+	MaxAttempts int
+	// InitialBackoff is how long murmur waits before the first retry. The wait
+	// doubles with each retry, up to MaxBackoff. A MaxBackoff of 0 means the
+	// wait keeps doubling.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Jitter is the fraction of each wait that is random, between 0 and 1.
+	// Randomness keeps processes that started together, like the pods of a
+	// deployment, from retrying all at once.
+	Jitter float64
+}
+
+// DefaultRetryPolicy is the policy murmur uses unless WithRetryPolicy says
+// otherwise.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Jitter:         0.5,
+}
+
+// backoff returns how long to wait before the given retry, starting at 1.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < retry && d > 0; i++ {
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+		// Without a limit, waits would eventually overflow.
+		if d > math.MaxInt64/2 {
+			d = math.MaxInt64
+			break
+		}
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+
+	jitter := min(max(p.Jitter, 0), 1)
+
+	return d - time.Duration(jitter*rand.Float64()*float64(d))
+}
+
+// resolveWithRetry resolves ref with a provider, and tries again as long as
+// the retry policy allows if resolution fails with a transient error.
+func resolveWithRetry(ctx context.Context, provider Provider, providerID, ref string, opts options) (string, error) {
+	policy := opts.retryPolicy
+	for attempt := 1; ; attempt++ {
+		value, err := provider.Resolve(ctx, ref)
+		if err == nil {
+			return value, nil
+		}
+		if attempt >= policy.MaxAttempts || ctx.Err() != nil || !isTransient(err) {
+			if attempt > 1 {
+				err = fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+			}
+			return "", err
+		}
+
+		wait := policy.backoff(attempt)
+		opts.logger.Warn("could not resolve reference, retrying", "provider", providerID, "ref", ref, "wait", wait.Round(time.Millisecond), "error", err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		case <-timer.C:
+		}
+	}
+}
+
+// resolveBatchWithRetry resolves refs with a batch provider, and tries again
+// to resolve those that failed with a transient error as long as the policy
+// allows.
+func resolveBatchWithRetry(ctx context.Context, provider BatchProvider, providerID string, refs []string, opts options) ([]string, []error) {
+	policy := opts.retryPolicy
+	values := make([]string, len(refs))
+	errs := make([]error, len(refs))
+
+	// Indices in refs of the secrets to resolve in the next attempt.
+	pending := make([]int, len(refs))
+	for i := range refs {
+		pending[i] = i
+	}
+
+	for attempt := 1; ; attempt++ {
+		batch := make([]string, len(pending))
+		for j, i := range pending {
+			batch[j] = refs[i]
+		}
+
+		batchValues, batchErrs := provider.ResolveBatch(ctx, batch)
+		if len(batchValues) != len(batch) || len(batchErrs) != len(batch) {
+			err := fmt.Errorf("provider returned %d values and %d errors for %d references", len(batchValues), len(batchErrs), len(batch))
+			for _, i := range pending {
+				errs[i] = err
+			}
+			return values, errs
+		}
+
+		var retries []int
+		for j, i := range pending {
+			values[i], errs[i] = batchValues[j], batchErrs[j]
+			if errs[i] != nil && isTransient(errs[i]) {
+				retries = append(retries, i)
+			}
+		}
+		if len(retries) == 0 {
+			return values, errs
+		}
+
+		giveUp := func() ([]string, []error) {
+			if attempt > 1 {
+				for _, i := range retries {
+					errs[i] = fmt.Errorf("gave up after %d attempts: %w", attempt, errs[i])
+				}
+			}
+			return values, errs
+		}
+
+		if attempt >= policy.MaxAttempts || ctx.Err() != nil {
+			return giveUp()
+		}
+
+		wait := policy.backoff(attempt)
+		opts.logger.Warn("could not resolve references, retrying", "provider", providerID, "count", len(retries), "wait", wait.Round(time.Millisecond), "error", errs[retries[0]])
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return giveUp()
+		case <-timer.C:
+		}
+
+		pending = retries
+	}
+}
+
+// isTransient reports whether err may go away if murmur tries again. Besides
+// errors that providers mark as transient, timeouts and connection failures
+// are.
+func isTransient(err error) bool {
+	if errors.Is(err, ErrTransient) {
+		return true
+	}
+	if errors.Is(err, ErrNotFound) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,82 @@
+package murmur
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/busser/murmur/pkg/murmur/providers"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Jitter:         0.5,
+	}
+
+	tt := []struct {
+		retry    int
+		min, max time.Duration
+	}{
+		{retry: 1, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{retry: 2, min: 100 * time.Millisecond, max: 200 * time.Millisecond},
+		{retry: 3, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		{retry: 5, min: 500 * time.Millisecond, max: time.Second},
+		{retry: 100, min: 500 * time.Millisecond, max: time.Second},
+	}
+
+	for _, tc := range tt {
+		t.Run(fmt.Sprint(tc.retry), func(t *testing.T) {
+			for range 100 {
+				d := policy.backoff(tc.retry)
+				if d < tc.min || d > tc.max {
+					t.Fatalf("backoff(%d) = %s, want between %s and %s", tc.retry, d, tc.min, tc.max)
+				}
+			}
+		})
+	}
+
+	policy.Jitter = 0
+	if d := policy.backoff(2); d != 200*time.Millisecond {
+		t.Errorf("backoff(2) without jitter = %s, want %s", d, 200*time.Millisecond)
+	}
+
+	// Without a maximum, the wait keeps doubling.
+	policy.MaxBackoff = 0
+	if d := policy.backoff(6); d != 3200*time.Millisecond {
+		t.Errorf("backoff(6) without maximum = %s, want %s", d, 3200*time.Millisecond)
+	}
+	if d := policy.backoff(1000); d != math.MaxInt64 {
+		t.Errorf("backoff(1000) without maximum = %s, want %s", d, time.Duration(math.MaxInt64))
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tt := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"plain", errors.New("access denied"), false},
+		{"marked", providers.Transient(errors.New("too many requests")), true},
+		{"wrapped", fmt.Errorf("oops: %w", providers.Transient(errors.New("bad gateway"))), true},
+		{"not found", providers.NotFound(errors.New("no such secret")), false},
+		{"connection reset", &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}, true},
+		{"unexpected EOF", fmt.Errorf("reading body: %w", io.ErrUnexpectedEOF), true},
+		{"canceled", context.Canceled, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isTransient(tc.err); got != tc.want {
+				t.Errorf("isTransient(%v) = %t, want %t", tc.err, got, tc.want)
+			}
+		})
+	}
+}
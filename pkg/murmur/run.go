@@ -1,13 +1,14 @@
 package murmur
 
 import (
+	"context"
 	"errors"
 	"io"
-	"log"
 	"os"
 	"os/exec"
 	"os/signal"
 	"sort"
+	"sync"
 
 	"github.com/busser/murmur/pkg/environ"
 )
@@ -19,13 +20,38 @@ var (
 )
 
 func Run(name string, args ...string) (exitCode int, err error) {
+	return RunWithOptions(name, args)
+}
+
+// RunWithOptions works like Run, but resolves secrets with the given options.
+func RunWithOptions(name string, args []string, opts ...Option) (exitCode int, err error) {
+	return NewResolver(opts...).Run(name, args...)
+}
+
+// Run works like the package-level Run, with the resolver's options.
+func (r *Resolver) Run(name string, args ...string) (exitCode int, err error) {
 	originalVars := environ.ToMap(os.Environ())
 
-	newVars, err := ResolveAll(originalVars)
+	newVars, leases, secrets, err := resolveAll(context.Background(), originalVars, r.opts)
 	if err != nil {
 		return 0, err
 	}
 
+	// Some secrets are only valid for as long as their lease is renewed. We
+	// renew leases while the sub process runs, and revoke them once it exits.
+	leaseCtx, stopRenewing := context.WithCancel(context.Background())
+	var renewing sync.WaitGroup
+	renewing.Add(1)
+	go func() {
+		defer renewing.Done()
+		keepLeasesAlive(leaseCtx, leases, r.opts.logger)
+	}()
+	defer func() {
+		stopRenewing()
+		renewing.Wait()
+		revokeLeases(leases, r.opts.logger)
+	}()
+
 	var overloaded []string
 	for name, original := range originalVars {
 		if newVars[name] != original {
@@ -35,7 +61,7 @@ func Run(name string, args ...string) (exitCode int, err error) {
 
 	sort.Strings(overloaded)
 	for _, name := range overloaded {
-		log.Printf("[murmur] overloading %s", name)
+		r.opts.logger.Info("overloading variable", "variable", name)
 	}
 
 	subCmd := exec.Command(name, args...)
@@ -44,6 +70,18 @@ func Run(name string, args ...string) (exitCode int, err error) {
 	subCmd.Stdout = runOut
 	subCmd.Stderr = runErr
 
+	if r.opts.redact {
+		patterns := redactionPatterns(secrets)
+		stdout := newRedactor(runOut, patterns)
+		stderr := newRedactor(runErr, patterns)
+		// The command's output is copied to the redactors until the command
+		// exits, after which the redactors must flush what they hold back.
+		defer stdout.Close()
+		defer stderr.Close()
+		subCmd.Stdout = stdout
+		subCmd.Stderr = stderr
+	}
+
 	if err := subCmd.Start(); err != nil {
 		return 1, err
 	}
@@ -13,6 +13,7 @@ func TestRun(t *testing.T) {
 		name         string
 		command      []string
 		env          []string
+		opts         []Option
 		wantExitCode int
 		wantOutput   string
 	}{
@@ -51,6 +52,19 @@ func TestRun(t *testing.T) {
 			wantExitCode: 0,
 			wantOutput:   "szechuan\n",
 		},
+		{
+			name: "redacted output",
+			command: []string{"/bin/sh", "-c", `
+				printenv SECRET_SAUCE
+				printf 'sauce=%s\n' "$(printenv SECRET_SAUCE | tr -d '\n' | base64)"
+				echo 'https://example.com/?sauce=sweet+%26+sour'
+				echo 'not secret'
+			`},
+			env:          []string{"SECRET_SAUCE=passthrough:sweet & sour"},
+			opts:         []Option{WithRedaction()},
+			wantExitCode: 0,
+			wantOutput:   "***\nsauce=***\nhttps://example.com/?sauce=***\nnot secret\n",
+		},
 	}
 
 	for _, tc := range tt {
@@ -80,7 +94,7 @@ func TestRun(t *testing.T) {
 				os.Setenv(k, v)
 			}
 
-			exitCode, err := Run(tc.command[0], tc.command[1:]...)
+			exitCode, err := RunWithOptions(tc.command[0], tc.command[1:], tc.opts...)
 			if err != nil {
 				t.Errorf("Run() returned an error: %v", err)
 			}
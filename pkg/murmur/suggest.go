@@ -0,0 +1,61 @@
+package murmur
+
+import "sort"
+
+// suggest returns the string in candidates that is closest to s, if it is close
+// enough for s to probably be a typo of it.
+func suggest(s string, candidates []string) (string, bool) {
+	var (
+		best         string
+		bestDistance = -1
+	)
+
+	for _, c := range candidates {
+		d := levenshtein(s, c)
+		// A short ID can become any other short ID in a couple of edits, so
+		// the allowed distance depends on the ID's length.
+		if d > 2 || 2*d >= len(c) {
+			continue
+		}
+		if bestDistance < 0 || d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+
+	return best, bestDistance >= 0
+}
+
+// levenshtein returns the minimum number of single-byte insertions, deletions,
+// and substitutions needed to turn a into b.
+func levenshtein(a, b string) int {
+	// Distances between a[:i] and b[:j], for the previous and current i.
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+// keys returns the keys of m, sorted.
+func keys[V any](m map[string]V) []string {
+	k := make([]string, 0, len(m))
+	for key := range m {
+		k = append(k, key)
+	}
+	sort.Strings(k)
+	return k
+}
@@ -0,0 +1,54 @@
+package murmur
+
+import "testing"
+
+func TestLevenshtein(t *testing.T) {
+	tt := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"awssm", "", 5},
+		{"", "awssm", 5},
+		{"awssm", "awssm", 0},
+		{"awsm", "awssm", 1},
+		{"awssmm", "awssm", 1},
+		{"awsmm", "awssm", 1},
+		{"jsonpth", "jsonpath", 1},
+		{"gcpms", "gcpsm", 2},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, tc := range tt {
+		if actual := levenshtein(tc.a, tc.b); actual != tc.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tc.a, tc.b, actual, tc.want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"awsps", "awssm", "azkv", "jsonpath", "op"}
+
+	tt := []struct {
+		s         string
+		want      string
+		wantFound bool
+	}{
+		{s: "awsm", want: "awssm", wantFound: true},
+		{s: "awsps", want: "awsps", wantFound: true},
+		{s: "awspss", want: "awsps", wantFound: true},
+		{s: "jsonpth", want: "jsonpath", wantFound: true},
+		{s: "azk", want: "azkv", wantFound: true},
+		{s: "https", wantFound: false},
+		{s: "ftp", wantFound: false},
+		{s: "o", wantFound: false},
+		{s: "postgres", wantFound: false},
+	}
+
+	for _, tc := range tt {
+		actual, found := suggest(tc.s, candidates)
+		if actual != tc.want || found != tc.wantFound {
+			t.Errorf("suggest(%q) = %q, %t, want %q, %t", tc.s, actual, found, tc.want, tc.wantFound)
+		}
+	}
+}
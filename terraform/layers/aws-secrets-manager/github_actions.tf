@@ -49,6 +49,25 @@ resource "aws_iam_role_policy" "github_actions_read_secret" {
           aws_secretsmanager_secret.example.arn,
         ]
       },
+      {
+        # BatchGetSecretValue does not support resource-level permissions.
+        # Callers still need GetSecretValue on each secret they fetch.
+        Action = [
+          "secretsmanager:BatchGetSecretValue",
+        ]
+        Effect   = "Allow"
+        Resource = "*"
+      },
+      {
+        Action = [
+          "ssm:GetParameter",
+        ]
+        Effect = "Allow"
+        Resource = [
+          aws_ssm_parameter.example.arn,
+          aws_ssm_parameter.plain.arn,
+        ]
+      },
     ]
   })
 }
@@ -0,0 +1,11 @@
+resource "aws_ssm_parameter" "example" {
+  name  = "/murmur/secret-sauce"
+  type  = "SecureString"
+  value = "szechuan"
+}
+
+resource "aws_ssm_parameter" "plain" {
+  name  = "/murmur/plain-sauce"
+  type  = "String"
+  value = "ketchup"
+}